@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/davido912-recipe-count-test-2020/internal/cli"
+	"github.com/davido912-recipe-count-test-2020/internal/diff"
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/goccy/go-json"
+	"github.com/spf13/cobra"
+)
+
+// ErrTooManyChanges is returned by the diff command when more entries changed than --max-changes allows
+var ErrTooManyChanges = errors.New("too many changes")
+
+// runDiff is the entrypoint of the diff command
+func runDiff(cmd *cobra.Command, args []string) error {
+	oldReport, err := readReport(args[0])
+	if err != nil {
+		return err
+	}
+	newReport, err := readReport(args[1])
+	if err != nil {
+		return err
+	}
+
+	d := diff.Compare(oldReport, newReport)
+	if cli.DiffOutput == cli.DiffFormatJSON {
+		out, err := json.MarshalIndent(d, "", " ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+		if err != nil {
+			return err
+		}
+	} else if err := d.WriteText(cmd.OutOrStdout()); err != nil {
+		return err
+	}
+
+	if cli.MaxChanges >= 0 && d.Changes > cli.MaxChanges {
+		return fmt.Errorf("%w: %d changes, at most %d allowed", ErrTooManyChanges, d.Changes, cli.MaxChanges)
+	}
+	return nil
+}
+
+// readReport reads a report written as JSON
+func readReport(path string) (*model.ReportModel, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	report, err := model.ReadReport(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return report, nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/davido912-recipe-count-test-2020/internal/aggregate"
+	"github.com/davido912-recipe-count-test-2020/internal/cli"
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/spf13/cobra"
+)
+
+// runMerge is the entrypoint of the merge command
+func runMerge(cmd *cobra.Command, args []string) error {
+	reports := make([]*model.ReportModel, 0, len(args))
+	for _, path := range args {
+		report, err := readReport(path)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, report)
+	}
+
+	merged, warnings, err := aggregate.MergeReports(reports...)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", warning)
+	}
+
+	if err := merged.Dumps(cli.Output); err != nil {
+		return err
+	}
+	return cli.Output.Close()
+}
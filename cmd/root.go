@@ -1,45 +1,168 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/davido912-recipe-count-test-2020/internal/aggregate"
 	"github.com/davido912-recipe-count-test-2020/internal/cli"
+	"github.com/davido912-recipe-count-test-2020/internal/dlq"
+	"github.com/davido912-recipe-count-test-2020/internal/input"
+	"github.com/davido912-recipe-count-test-2020/internal/model"
 	"github.com/davido912-recipe-count-test-2020/internal/processor"
-	"github.com/rs/zerolog/log"
+	"github.com/goccy/go-json"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
-var rootCmd = cli.NewRootCmd(func(cmd *cobra.Command, args []string) error {
-	log.Debug().Msgf("opening file in path: %s", cli.Filepath)
-	dataFile, err := os.Open(cli.Filepath)
+var rootCmd = newRootCmd()
+
+func newRootCmd() *cobra.Command {
+	return cli.NewRootCmd(run)
+}
+
+// run is the entrypoint of the root command
+func run(cmd *cobra.Command, args []string) error {
+	aggrInput := &aggregate.AggregatorInput{
+		Metrics:                cli.Metrics,
+		Queries:                cli.Queries,
+		Terms:                  cli.MatchRecipeTerms,
+		TermMatcher:            cli.RecipeMatcher,
+		TopPostcodes:           cli.TopPostcodes,
+		PostcodeCounts:         cli.PostcodeCounts,
+		Weekdays:               cli.PerWeekday,
+		HourlyLoad:             cli.HourlyLoad,
+		HourlyLoadByWeekday:    cli.HourlyByWeekday,
+		HourlyLoadTopPostcodes: cli.HourlyTop,
+		TimeFormat:             cli.TimeFormat,
+		MatchModes:             cli.MatchModes,
+	}
+
+	filePaths, err := input.Resolve(cli.Filepaths)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = dataFile.Close() }()
 
-	aggrInput := &aggregate.AggregatorInput{
-		Postcode:     cli.Postcode,
-		DeliveryFrom: cli.DeliveryFrom,
-		DeliveryTo:   cli.DeliveryTo,
-		Terms:        cli.MatchRecipeTerms,
+	var (
+		dlqChan chan *model.RejectedRecipe
+		dlqSink *dlq.FileSink
+	)
+	if cli.DLQPath != "" {
+		dlqSink, err = dlq.NewFileSink(cli.DLQPath)
+		if err != nil {
+			return err
+		}
+		dlqChan = make(chan *model.RejectedRecipe, dlq.ChanSize)
+		dlqSink.Listen(dlqChan)
 	}
 
-	proc := processor.NewProcessor(2024, aggrInput, nil)
-	report, err := proc.Process(dataFile)
+	proc := processor.NewProcessor(2024, aggrInput, dlqChan)
+	proc.SetInputFormat(cli.InputFormat)
+	proc.SetErrorBudget(cli.ErrorBudget)
+
+	ctx, cancel := processingContext(cmd.Context())
+	defer cancel()
+
+	proc.Start(ctx)
+	err = input.WalkAll(ctx, filePaths, cli.ArchiveMember, func(name string, data io.Reader) error {
+		if err := proc.Feed(name, data); err != nil {
+			return fmt.Errorf("failed parsing input file %s: %w", name, err)
+		}
+		return nil
+	})
+	report, finishErr := proc.Finish()
+
+	if dlqSink != nil {
+		close(dlqChan)
+		if err := dlqSink.Close(); err != nil {
+			return fmt.Errorf("failed writing rejected records to %s: %w", cli.DLQPath, err)
+		}
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%d rejected records written to %s\n", dlqSink.Written(), cli.DLQPath)
+	}
+
+	if cli.PerFileBreakdown {
+		report.SetPerFile(proc.GetFileStats())
+	}
+	report.SetSingleQueryCompat(cli.SingleQuery)
+
+	// exceeding the error budget or an interruption aborts the walk, so it takes precedence over the error the walk
+	// returned
+	if errors.Is(finishErr, processor.ErrIncomplete) {
+		return reportIncomplete(report, finishErr)
+	}
+	if finishErr != nil {
+		return reportErrorBudget(cmd, finishErr)
+	}
 	if err != nil {
 		return err
 	}
 
-	err = report.Dumps(cli.Output)
+	err = cli.Renderer.Render(report, cli.Output)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = cli.Output.Close() }()
 
 	return nil
-})
+}
+
+// processingContext returns a context that is done on SIGINT/SIGTERM or once the timeout (if set) elapsed. once done,
+// the default signal behaviour is restored so that a second signal kills a run blocked on reading its input
+func processingContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if cli.Timeout <= 0 {
+		return ctx, stop
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, cli.Timeout)
+	return timeoutCtx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// reportIncomplete writes the partial report marked as incomplete when requested, the run fails either way
+func reportIncomplete(report *model.ReportModel, err error) error {
+	if !cli.PartialReport {
+		return err
+	}
+
+	if dumpErr := cli.Renderer.Render(report, cli.Output); dumpErr != nil {
+		return dumpErr
+	}
+	_ = cli.Output.Close()
+
+	return err
+}
+
+// reportErrorBudget writes a structured summary to stderr when the error budget was exceeded
+func reportErrorBudget(cmd *cobra.Command, err error) error {
+	var budgetErr *processor.ErrorBudgetError
+	if !errors.As(err, &budgetErr) {
+		return err
+	}
+
+	summary, marshalErr := json.MarshalIndent(budgetErr, "", "    ")
+	if marshalErr != nil {
+		return err
+	}
+	_, _ = fmt.Fprintln(cmd.ErrOrStderr(), string(summary))
+
+	return err
+}
 
-func Run() {
+// Run executes the CLI, the error the command failed with was already written to stderr
+func Run() error {
 	rootCmd.AddCommand(cli.NewVersionCmd())
-	cli.MustCli(rootCmd)
+	rootCmd.AddCommand(cli.NewDiffCmd(runDiff))
+	rootCmd.AddCommand(cli.NewMergeCmd(runMerge))
+	return rootCmd.Execute()
 }
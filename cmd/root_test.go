@@ -5,11 +5,16 @@
 package cmd
 
 import (
+	"bytes"
+	"compress/gzip"
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/davido912-recipe-count-test-2020/internal/processor"
 	"github.com/davido912-recipe-count-test-2020/internal/testutils"
 	"github.com/stretchr/testify/require"
 	"os"
 	"path"
 	"testing"
+	"time"
 )
 
 func TestRun(t *testing.T) {
@@ -21,6 +26,7 @@ func TestRun(t *testing.T) {
 	}
 	defer func() { _ = os.Remove(outputFile.Name()) }()
 
+	rootCmd = newRootCmd()
 	rootCmd.SetArgs([]string{
 		"--file", inputFilePath,
 		"-m", "Dill",
@@ -30,7 +36,7 @@ func TestRun(t *testing.T) {
 		"-o", outputFile.Name(),
 	})
 
-	Run()
+	require.Nil(t, Run())
 
 	got, err := os.ReadFile(outputFile.Name())
 	if err != nil {
@@ -44,3 +50,167 @@ func TestRun(t *testing.T) {
 	require.JSONEq(t, string(want), string(got))
 
 }
+
+func TestRun_gzipInput(t *testing.T) {
+	testDataDirPath := path.Join(testutils.GitRoot, "testdata")
+	input, err := os.ReadFile(path.Join(testDataDirPath, "input.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	inputFile, err := os.CreateTemp("", "input.json.gz")
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = os.Remove(inputFile.Name()) }()
+
+	gz := gzip.NewWriter(inputFile)
+	if _, err = gz.Write(input); err != nil {
+		panic(err)
+	}
+	_ = gz.Close()
+	_ = inputFile.Close()
+
+	outputFile, err := os.CreateTemp("", "output.json")
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = os.Remove(outputFile.Name()) }()
+
+	rootCmd = newRootCmd()
+	rootCmd.SetArgs([]string{
+		"--file", inputFile.Name(),
+		"-m", "Dill",
+		"-p", "10335",
+		"--from", "4PM",
+		"--to", "10PM",
+		"-o", outputFile.Name(),
+	})
+
+	require.Nil(t, Run())
+
+	got, err := os.ReadFile(outputFile.Name())
+	if err != nil {
+		panic(err)
+	}
+	want, err := os.ReadFile(path.Join(testDataDirPath, "output.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	require.JSONEq(t, string(want), string(got))
+}
+
+func TestRun_stdinTimeout(t *testing.T) {
+	// a single record is written to stdin, which is then left open
+	stdin, stdinWriter, err := os.Pipe()
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = stdinWriter.Close() }()
+	_, err = stdinWriter.WriteString(`{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}` + "\n")
+	if err != nil {
+		panic(err)
+	}
+
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = origStdin }()
+
+	outputFile, err := os.CreateTemp("", "output.json")
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = os.Remove(outputFile.Name()) }()
+
+	rootCmd = newRootCmd()
+	rootCmd.SetArgs([]string{"-f", "-", "--timeout", "100ms", "--partial-report", "-o", outputFile.Name()})
+
+	start := time.Now()
+	require.ErrorIs(t, Run(), processor.ErrIncomplete)
+	require.Less(t, time.Since(start), 2*time.Second)
+
+	got, err := os.ReadFile(outputFile.Name())
+	if err != nil {
+		panic(err)
+	}
+	report, err := model.ReadReport(bytes.NewReader(got))
+	require.Nil(t, err)
+	require.True(t, report.Incomplete)
+	require.Equal(t, 1, report.UniqueRecipeCount)
+}
+
+func TestRun_diff(t *testing.T) {
+	testDataDirPath := path.Join(testutils.GitRoot, "testdata")
+	report, err := os.ReadFile(path.Join(testDataDirPath, "output.json"))
+	if err != nil {
+		panic(err)
+	}
+
+	// the new report counts more deliveries of the postcode query
+	newReportFile, err := os.CreateTemp("", "output.json")
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = os.Remove(newReportFile.Name()) }()
+	if _, err = newReportFile.Write(bytes.Replace(report, []byte("144"), []byte("150"), 1)); err != nil {
+		panic(err)
+	}
+	_ = newReportFile.Close()
+
+	out := bytes.NewBuffer([]byte{})
+	oldReportPath := path.Join(testDataDirPath, "output.json")
+	rootCmd = newRootCmd()
+	rootCmd.SetOut(out)
+	rootCmd.SetArgs([]string{"diff", "--max-changes", "1", oldReportPath, newReportFile.Name()})
+
+	require.Nil(t, Run())
+
+	want := "count per postcode and time:\n  ~ 10335 4PM - 10PM  144 -> 150 (+6)\n1 changes\n"
+	require.Equal(t, want, out.String())
+
+	// exceeding --max-changes still writes the diff but fails the run
+	out.Reset()
+	rootCmd = newRootCmd()
+	rootCmd.SetOut(out)
+	rootCmd.SetArgs([]string{"diff", "--max-changes", "0", oldReportPath, newReportFile.Name()})
+
+	require.ErrorIs(t, Run(), ErrTooManyChanges)
+	require.Equal(t, want, out.String())
+}
+
+func TestRun_merge(t *testing.T) {
+	testDataDirPath := path.Join(testutils.GitRoot, "testdata")
+	reportPath := path.Join(testDataDirPath, "output.json")
+	outputFile, err := os.CreateTemp("", "merged.json")
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = os.Remove(outputFile.Name()) }()
+
+	rootCmd = newRootCmd()
+	rootCmd.SetArgs([]string{"merge", "-o", outputFile.Name(), reportPath, reportPath})
+	stderr := bytes.NewBuffer([]byte{})
+	rootCmd.SetErr(stderr)
+
+	require.Nil(t, Run())
+	require.Equal(t, "warning: leaving out sections busiest_postcode and top_postcodes, they can only be merged from "+
+		"postcode_counts (--postcode-counts)\n", stderr.String())
+
+	got, err := os.ReadFile(outputFile.Name())
+	if err != nil {
+		panic(err)
+	}
+	merged, err := model.ReadReport(bytes.NewReader(got))
+	require.Nil(t, err)
+
+	// the report has no postcode counts, the busiest postcode cannot be merged
+	require.True(t, merged.Omitted(model.SectionBusiestPostcode))
+	require.Equal(t, 2, merged.UniqueRecipeCount)
+	require.Equal(t, model.RecipeCounts{
+		{Recipe: "Creamy Dill Chicken", RecipeCount: 1278},
+		{Recipe: "Spicy Taco", RecipeCount: 548},
+	}, merged.CountPerRecipe)
+	require.Equal(t, 288, merged.CountPerPostcodeAndTime[0].DeliveryCount)
+	require.Equal(t, 1826, merged.DataQuality.RecordsRead)
+}
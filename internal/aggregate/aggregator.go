@@ -1,67 +1,174 @@
 package aggregate
 
 import (
+	"context"
 	"github.com/davido912-recipe-count-test-2020/internal/model"
 	"github.com/rs/zerolog/log"
 )
 
 type AggregatorInput struct {
 
-	// Postcode, DeliveryFrom, DeliveryTo are used for functional requirement 4
-	Postcode                 string
-	DeliveryFrom, DeliveryTo *model.DeliveryTime
+	// Metrics are the names of the registered metrics to compute, the DefaultMetrics when empty. the optional metrics
+	// enabled by the other fields (TopPostcodes, PostcodeCounts, Weekdays and HourlyLoad) are computed as well
+	Metrics []string
 
-	// Terms used for functional requirement 5 - matching recipes
-	Terms []string
+	// Queries are used for functional requirement 4, all of them are evaluated in a single pass
+	Queries []PostcodeQuery
+
+	// MatchModes decide when a delivery matches the timespan of a query, the first mode decides the delivery count of
+	// the query. when set, the result of every mode is detailed per query
+	MatchModes []model.MatchMode
+
+	// TimeFormat controls how the times of the queries are rendered in the report
+	TimeFormat model.TimeFormat
+
+	// Weekdays enables the deliveries and recipes per weekday aggregates
+	Weekdays bool
+
+	// HourlyLoad enables the histogram of the deliveries active during every hour of the day, HourlyLoadByWeekday
+	// details it per weekday and HourlyLoadTopPostcodes is the number of busiest postcodes it is broken out for
+	HourlyLoad             bool
+	HourlyLoadByWeekday    bool
+	HourlyLoadTopPostcodes int
+
+	// TopPostcodes is the number of busiest postcodes ranked in the report, 0 leaves the ranking out
+	TopPostcodes int
+
+	// PostcodeCounts enables the deliveries of every postcode, which reports need to be merged exactly
+	PostcodeCounts bool
+
+	// Terms used for functional requirement 5 - matching recipes. TermMatcher, compiled from the terms, decides how the
+	// terms match the recipe names, without it the terms are matched as case-sensitive substrings
+	Terms       []string
+	TermMatcher *TermMatcher
+
+	// shared holds the aggregators shared by the metrics of a run, see NewAggregator
+	shared *sharedAggregators
 }
 
+// Aggregator fans the recipes out to the selected metrics
 type Aggregator struct {
-	*PostcodeAggregator
-	*RecipeAggregator
+	metrics []Metric
+
+	// aggregated is called with every recipe once all the metrics aggregated it
+	aggregated func(recipe *model.Recipe)
 }
 
-// NewAggregator returns an instance that calculates postcode and recipe metrics. The parameters passed to
-// the aggregator are used to collect additional metrics
+// NewAggregator returns an instance that calculates the metrics selected by aggrInput. unknown metrics are skipped,
+// the metric names are expected to be validated beforehand. the built-in metrics share their recipe and postcode
+// aggregators
 func NewAggregator(aggrInput *AggregatorInput) *Aggregator {
-	recipeAggregator := &RecipeAggregator{
-		recipeMap: make(recipeMap, DistinctRecipeCap),
-		recipeMatcher: recipeMatcher{
-			matches: make([]string, 0, DistinctRecipeCap),
-			terms:   aggrInput.Terms,
-		},
+	runInput := *aggrInput
+	runInput.shared = &sharedAggregators{}
+
+	aggr := &Aggregator{}
+	for _, name := range runInput.metricNames() {
+		m, err := NewMetric(name, &runInput)
+		if err != nil {
+			log.Error().Msgf("skipping metric: %s", err)
+			continue
+		}
+		aggr.metrics = append(aggr.metrics, m)
+	}
+	return aggr
+}
+
+// metricNames returns the selected metrics, or the default metrics when none were selected, followed by the optional
+// metrics enabled by the other fields
+func (in *AggregatorInput) metricNames() []string {
+	names := in.Metrics
+	if len(names) == 0 {
+		names = DefaultMetrics
+	}
+
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[name] = true
+	}
+	optional := []struct {
+		name    string
+		enabled bool
+	}{
+		{name: model.SectionTopPostcodes, enabled: in.TopPostcodes > 0},
+		{name: model.SectionPostcodeCounts, enabled: in.PostcodeCounts},
+		{name: model.SectionCountPerWeekday, enabled: in.Weekdays},
+		{name: model.SectionHourlyLoad, enabled: in.HourlyLoad},
 	}
-	postcodeAggregator := &PostcodeAggregator{
-		postcodeMap: make(postcodeMap, DistinctPostcodesCap),
-		postcodeTimeCount: model.PostcodeTimeCount{
-			Postcode:      aggrInput.Postcode,
-			From:          aggrInput.DeliveryFrom.Raw(),
-			To:            aggrInput.DeliveryTo.Raw(),
-			DeliveryCount: 0,
-		},
-		aggrDeliveryTo:   aggrInput.DeliveryTo,
-		aggrDeliveryFrom: aggrInput.DeliveryFrom,
+	for _, metric := range optional {
+		if metric.enabled && !selected[metric.name] {
+			names = append(names, metric.name)
+		}
 	}
 
-	return &Aggregator{
-		PostcodeAggregator: postcodeAggregator,
-		RecipeAggregator:   recipeAggregator,
+	return names
+}
+
+// sharedAggregators returns the aggregators shared by the metrics of the run, a metric created on its own (outside of
+// NewAggregator) gets aggregators of its own
+func (in *AggregatorInput) sharedAggregators() *sharedAggregators {
+	if in.shared == nil {
+		return &sharedAggregators{}
 	}
+	return in.shared
 }
 
-func (a *Aggregator) Aggregate(recipeChan chan *model.Recipe) {
+// ValidateRecipe ensures the recipe respects the constraints of the aggregators (e.g. postcode and recipe name
+// lengths). recipes failing validation must not be aggregated, the metrics rely on it and ignore the validation
+// errors of the aggregators
+func ValidateRecipe(recipe *model.Recipe) error {
+	if err := validatePostcode(recipe); err != nil {
+		return err
+	}
+	return validateRecipeName(recipe)
+}
+
+// Aggregate consumes the recipes from recipeChan until it is closed or ctx is done, after which the aggregates hold
+// whatever was consumed up to that point
+func (a *Aggregator) Aggregate(ctx context.Context, recipeChan chan *model.Recipe) {
 
 	// consume all events from channel
-	a.listen(recipeChan, func(recipe *model.Recipe) {
-		a.PostcodeAggregator.aggregate(recipe)
-		a.RecipeAggregator.aggregate(recipe)
+	a.listen(ctx, recipeChan, func(recipe *model.Recipe) {
+		for _, m := range a.metrics {
+			m.Aggregate(recipe)
+		}
+		if a.aggregated != nil {
+			a.aggregated(recipe)
+		}
 	})
 
-	a.RecipeAggregator.postAggregate()
+	for _, m := range a.metrics {
+		m.Finalize()
+	}
+}
+
+// SetAggregatedFunc sets a func called with every recipe once all the metrics aggregated it. recipes left in
+// recipeChan once ctx is done are not aggregated, so it is not called for them
+func (a *Aggregator) SetAggregatedFunc(aggregated func(recipe *model.Recipe)) {
+	a.aggregated = aggregated
+}
+
+// Contribute adds the section of every metric to report. the sections of the default metrics that were not selected
+// are left out of the report
+func (a *Aggregator) Contribute(report *model.ReportModel) {
+	contributed := make(map[string]bool, len(a.metrics))
+	for _, m := range a.metrics {
+		m.Contribute(report)
+		contributed[m.Name()] = true
+	}
+
+	for _, name := range DefaultMetrics {
+		if !contributed[name] {
+			report.Omit(name)
+		}
+	}
 }
 
-func (a *Aggregator) listen(recipeChan chan *model.Recipe, processFunc func(*model.Recipe)) {
+func (a *Aggregator) listen(ctx context.Context, recipeChan chan *model.Recipe, processFunc func(*model.Recipe)) {
 	for {
 		select {
+		case <-ctx.Done():
+			log.Debug().Msgf("stopped consuming recipe channel: %s", ctx.Err())
+			return
 		case recipe, ok := <-recipeChan:
 			if ok == false {
 				log.Debug().Msg("recipe channel is empty and closed. done processing.")
@@ -1,9 +1,11 @@
 package aggregate
 
 import (
+	"context"
 	"github.com/davido912-recipe-count-test-2020/internal/model"
 	"github.com/davido912-recipe-count-test-2020/internal/testutils"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -19,13 +21,29 @@ func TestAggregator_listen(t *testing.T) {
 	var processed int
 	aggr := Aggregator{}
 
-	aggr.listen(recipeChan, func(_ *model.Recipe) {
+	aggr.listen(context.Background(), recipeChan, func(_ *model.Recipe) {
 		processed++
 	})
 
 	assert.Equal(t, len(recipes), processed)
 }
 
+func TestAggregator_listenCancelled(t *testing.T) {
+	// the channel is never closed, so listen only returns because the context is done
+	recipeChan := make(chan *model.Recipe)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var processed int
+	aggr := Aggregator{}
+
+	aggr.listen(ctx, recipeChan, func(_ *model.Recipe) {
+		processed++
+	})
+
+	assert.Equal(t, 0, processed)
+}
+
 // all sub-functionalities inside this function are already tested directly in other tests
 // this test just tests that it runs without panicing
 func TestAggregator_Aggregate(t *testing.T) {
@@ -38,12 +56,82 @@ func TestAggregator_Aggregate(t *testing.T) {
 	close(recipeChan)
 
 	aggrInput := &AggregatorInput{
-		Postcode:     "10245",
-		DeliveryFrom: testutils.MockDeliveryTime("10AM"),
-		DeliveryTo:   testutils.MockDeliveryTime("3PM"),
-		Terms:        []string{"ea"},
+		Queries: []PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+			},
+		},
+		Terms: []string{"ea"},
+	}
+	aggr := NewAggregator(aggrInput)
+	var aggregated int
+	aggr.SetAggregatedFunc(func(_ *model.Recipe) {
+		aggregated++
+	})
+
+	aggr.Aggregate(context.Background(), recipeChan)
+	assert.Equal(t, len(recipes), aggregated)
+}
+
+func TestAggregator_sharedAggregators(t *testing.T) {
+	recipes := testutils.MockRecipes()
+	recipeChan := make(chan *model.Recipe, len(recipes))
+
+	for _, recipe := range recipes {
+		recipeChan <- recipe
 	}
+	close(recipeChan)
+
+	aggrInput := &AggregatorInput{TopPostcodes: 1, PostcodeCounts: true}
 	aggr := NewAggregator(aggrInput)
+	assert.Nil(t, aggrInput.shared)
+
+	aggr.Aggregate(context.Background(), recipeChan)
+	report := model.NewReportModel()
+	aggr.Contribute(report)
+
+	// every recipe is counted once by the aggregators the metrics share
+	assert.Equal(t, 5, report.UniqueRecipeCount)
+	assert.Contains(t, report.CountPerRecipe, model.RecipeCount{Recipe: "Honey", RecipeCount: 2})
+	assert.Equal(t, model.PostcodeCount{Postcode: "10245", DeliveryCount: 3}, report.BusiestPostcode)
+	assert.Equal(t, []model.RankedPostcodeCount{{Rank: 1, Postcode: "10245", DeliveryCount: 3, Share: 50}},
+		report.TopPostcodes)
+	assert.Contains(t, report.PostcodeCounts, model.PostcodeCount{Postcode: "10311", DeliveryCount: 2})
+}
 
-	aggr.Aggregate(recipeChan)
+func TestValidateRecipe(t *testing.T) {
+	tcs := []struct {
+		name    string
+		recipe  *model.Recipe
+		wantErr error
+	}{
+		{
+			name:    "valid recipe",
+			recipe:  &model.Recipe{Postcode: "10245", Recipe: "Honey"},
+			wantErr: nil,
+		},
+		{
+			name:    "postcode too long",
+			recipe:  &model.Recipe{Postcode: "1234567891011", Recipe: "Honey"},
+			wantErr: ErrPostcodeTooLong,
+		},
+		{
+			name:    "recipe name too long",
+			recipe:  &model.Recipe{Postcode: "10245", Recipe: strings.Repeat("a", RecipeNameLenConstraint+1)},
+			wantErr: ErrRecipeNameTooLong,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateRecipe(tc.recipe)
+			if tc.wantErr == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr)
+			}
+		})
+	}
 }
@@ -0,0 +1,120 @@
+package aggregate
+
+import (
+	"sort"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+)
+
+// hourlyWindow identifies a delivery window by its start minute of the week and its duration in minutes
+type hourlyWindow struct {
+	start, duration int
+}
+
+// windowCounts counts the deliveries per delivery window. deliveries share a handful of windows, so the hourly load
+// is derived from the window counts once aggregating is done instead of being updated for every delivery
+type windowCounts map[hourlyWindow]int
+
+// HourlyAggregator builds the hour of the day (and optionally weekday x hour) histograms of the deliveries active
+// during every hour, for all the deliveries and for the busiest postcodes
+type HourlyAggregator struct {
+	byWeekday    bool
+	topPostcodes int
+
+	windows         windowCounts
+	postcodeWindows map[string]windowCounts
+}
+
+func newHourlyAggregator(aggrInput *AggregatorInput) *HourlyAggregator {
+	return &HourlyAggregator{
+		byWeekday:       aggrInput.HourlyLoadByWeekday,
+		topPostcodes:    aggrInput.HourlyLoadTopPostcodes,
+		windows:         make(windowCounts),
+		postcodeWindows: make(map[string]windowCounts),
+	}
+}
+
+// aggregate counts the delivery window of recipe globally and for its postcode
+func (ha *HourlyAggregator) aggregate(recipe *model.Recipe) {
+	delivery := recipe.DeliveryWindow()
+	window := hourlyWindow{start: delivery.Start(), duration: delivery.Duration()}
+	ha.windows[window]++
+
+	if ha.topPostcodes <= 0 {
+		return
+	}
+	postcodeWindows, ok := ha.postcodeWindows[recipe.Postcode]
+	if !ok {
+		postcodeWindows = make(windowCounts)
+		ha.postcodeWindows[recipe.Postcode] = postcodeWindows
+	}
+	postcodeWindows[window]++
+}
+
+// GetHourlyLoad returns the hourly load of all the deliveries and of the busiest postcodes, ranked by their number of
+// deliveries with ties broken by postcode in ascending order
+func (ha *HourlyAggregator) GetHourlyLoad() *model.HourlyLoadReport {
+	totals := make(map[string]int, len(ha.postcodeWindows))
+	postcodes := make([]string, 0, len(ha.postcodeWindows))
+	for postcode, windows := range ha.postcodeWindows {
+		postcodes = append(postcodes, postcode)
+		totals[postcode] = windows.total()
+	}
+	sort.Slice(postcodes, func(i, j int) bool {
+		return rankedBefore(postcodes[i], totals[postcodes[i]], postcodes[j], totals[postcodes[j]])
+	})
+	if len(postcodes) > ha.topPostcodes {
+		postcodes = postcodes[:ha.topPostcodes]
+	}
+
+	postcodeLoads := make([]model.PostcodeHourlyLoad, 0, len(postcodes))
+	for _, postcode := range postcodes {
+		postcodeLoads = append(postcodeLoads, model.PostcodeHourlyLoad{
+			Postcode:      postcode,
+			DeliveryCount: totals[postcode],
+			HourlyLoad:    ha.postcodeWindows[postcode].hourlyLoad(ha.byWeekday),
+		})
+	}
+
+	return &model.HourlyLoadReport{
+		HourlyLoad: ha.windows.hourlyLoad(ha.byWeekday),
+		Postcodes:  postcodeLoads,
+	}
+}
+
+// total returns the number of deliveries of all the windows
+func (wc windowCounts) total() int {
+	var total int
+	for _, count := range wc {
+		total += count
+	}
+	return total
+}
+
+// hourlyLoad returns the number of deliveries active during every hour of the day, summed over all the weekdays, and
+// detailed per weekday starting on Monday when byWeekday is set
+func (wc windowCounts) hourlyLoad(byWeekday bool) model.HourlyLoad {
+	var hoursOfWeek [model.HoursPerWeek]int
+	for window, count := range wc {
+		for _, hour := range model.HoursOfWeek(window.start, window.duration) {
+			hoursOfWeek[hour] += count
+		}
+	}
+
+	hourlyLoad := model.HourlyLoad{Hours: make([]int, model.HoursPerDay)}
+	for hour, count := range hoursOfWeek {
+		hourlyLoad.Hours[hour%model.HoursPerDay] += count
+	}
+	if !byWeekday {
+		return hourlyLoad
+	}
+
+	for _, weekday := range model.Weekdays {
+		first := int(weekday) * model.HoursPerDay
+		hourlyLoad.ByWeekday = append(hourlyLoad.ByWeekday, model.WeekdayHourlyLoad{
+			Weekday: weekday.String(),
+			Hours:   append([]int(nil), hoursOfWeek[first:first+model.HoursPerDay]...),
+		})
+	}
+	return hourlyLoad
+}
@@ -0,0 +1,97 @@
+package aggregate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/davido912-recipe-count-test-2020/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockHourlyRecipe(postcode string, weekday time.Weekday, from, to string) *model.Recipe {
+	return &model.Recipe{
+		Postcode: postcode,
+		Weekday:  weekday,
+		From:     testutils.MockDeliveryTime(from),
+		To:       testutils.MockDeliveryTime(to),
+	}
+}
+
+// mockHours returns the hours of a day with count deliveries active during each of the given hours
+func mockHours(count int, hours ...int) []int {
+	day := make([]int, model.HoursPerDay)
+	for _, hour := range hours {
+		day[hour] += count
+	}
+	return day
+}
+
+func TestHourlyAggregator_GetHourlyLoad(t *testing.T) {
+	recipes := model.Recipes{
+		mockHourlyRecipe("10120", time.Monday, "10AM", "12PM"),
+		mockHourlyRecipe("10120", time.Monday, "10:30AM", "11AM"),
+		mockHourlyRecipe("10245", time.Saturday, "11PM", "1AM"),
+		mockHourlyRecipe("10311", time.Saturday, "11PM", "1AM"),
+	}
+
+	tcs := []struct {
+		name      string
+		aggrInput AggregatorInput
+		want      *model.HourlyLoadReport
+	}{
+		{
+			name:      "without postcodes",
+			aggrInput: AggregatorInput{HourlyLoad: true},
+			want: &model.HourlyLoadReport{
+				HourlyLoad: model.HourlyLoad{Hours: []int{2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+					0, 0, 2}},
+				Postcodes: []model.PostcodeHourlyLoad{},
+			},
+		},
+		{
+			name:      "busiest postcodes ranked by deliveries then postcode",
+			aggrInput: AggregatorInput{HourlyLoad: true, HourlyLoadTopPostcodes: 2},
+			want: &model.HourlyLoadReport{
+				HourlyLoad: model.HourlyLoad{Hours: []int{2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+					0, 0, 2}},
+				Postcodes: []model.PostcodeHourlyLoad{
+					{Postcode: "10120", DeliveryCount: 2, HourlyLoad: model.HourlyLoad{Hours: []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+						2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}}},
+					{Postcode: "10245", DeliveryCount: 1, HourlyLoad: model.HourlyLoad{Hours: mockHours(1, 0, 23)}},
+				},
+			},
+		},
+		{
+			name:      "by weekday",
+			aggrInput: AggregatorInput{HourlyLoad: true, HourlyLoadByWeekday: true},
+			want: &model.HourlyLoadReport{
+				HourlyLoad: model.HourlyLoad{
+					Hours: []int{2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2},
+					ByWeekday: []model.WeekdayHourlyLoad{
+						{Weekday: "Monday", Hours: []int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+							0, 0, 0}},
+						{Weekday: "Tuesday", Hours: mockHours(0)},
+						{Weekday: "Wednesday", Hours: mockHours(0)},
+						{Weekday: "Thursday", Hours: mockHours(0)},
+						{Weekday: "Friday", Hours: mockHours(0)},
+						{Weekday: "Saturday", Hours: mockHours(2, 23)},
+						// deliveries crossing midnight are active on the next day as well
+						{Weekday: "Sunday", Hours: mockHours(2, 0)},
+					},
+				},
+				Postcodes: []model.PostcodeHourlyLoad{},
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			aggr := newHourlyAggregator(&tc.aggrInput)
+			for _, recipe := range recipes {
+				aggr.aggregate(recipe)
+			}
+			assert.Equal(t, tc.want, aggr.GetHourlyLoad())
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+)
+
+var ErrUnmergeableReports = errors.New("reports cannot be merged")
+
+// reportMerger merges the sections of reports computed over distinct partitions of the input. warnings tells what
+// was left out of the merged report and why
+type reportMerger struct {
+	reports  []*model.ReportModel
+	merged   *model.ReportModel
+	recipes  recipeMap
+	warnings []string
+}
+
+// MergeReports combines reports computed over distinct partitions of the input into the report of the whole input.
+// recipe counts, postcode/time-window counts (which must be the same queries in every report), weekday counts, the
+// hourly load and the data quality are summed, the unique recipe count and the matches are recomputed from the summed
+// recipe counts. the busiest and top postcodes can only be merged from the postcode_counts section, they are left out
+// when any of the reports does not have it. custom sections and the hourly load of the busiest postcodes cannot be
+// merged either and are left out as well. the returned warnings tell the sections that were left out
+func MergeReports(reports ...*model.ReportModel) (*model.ReportModel, []string, error) {
+	if len(reports) == 0 {
+		return nil, nil, fmt.Errorf("%w: no reports", ErrUnmergeableReports)
+	}
+
+	m := &reportMerger{reports: reports, merged: model.NewReportModel(), recipes: make(recipeMap)}
+	if err := m.mergePostcodeTimeCounts(); err != nil {
+		return nil, nil, err
+	}
+	m.mergeRecipes()
+	m.mergeMatches()
+	m.mergePostcodes()
+	m.mergeWeekdays()
+	m.mergeHourlyLoad()
+	m.mergeDataQuality()
+
+	for _, report := range reports {
+		for _, section := range report.CustomSections() {
+			m.warnf("leaving out custom section %s, it cannot be merged", section)
+		}
+	}
+	return m.merged, m.warnings, nil
+}
+
+// warnf records a warning about something left out of the merged report
+func (m *reportMerger) warnf(format string, args ...interface{}) {
+	m.warnings = append(m.warnings, fmt.Sprintf(format, args...))
+}
+
+// every returns whether has holds for every report. when it holds for some of the reports only, the optional section
+// they have is left out with a warning
+func (m *reportMerger) every(section string, has func(report *model.ReportModel) bool) bool {
+	var count int
+	for _, report := range m.reports {
+		if has(report) {
+			count++
+		}
+	}
+	if count > 0 && count < len(m.reports) {
+		m.warnf("leaving out section %s, %d of the reports do not have it", section, len(m.reports)-count)
+	}
+	return count == len(m.reports)
+}
+
+// all returns whether every report has section, a default section is left out of the merged report when any of the
+// reports omitted it
+func (m *reportMerger) all(section string) bool {
+	var omitted int
+	for _, report := range m.reports {
+		if report.Omitted(section) {
+			omitted++
+		}
+	}
+	if omitted > 0 {
+		if omitted < len(m.reports) {
+			m.warnf("leaving out section %s, %d of the reports do not have it", section, omitted)
+		}
+		m.merged.Omit(section)
+		return false
+	}
+	return true
+}
+
+// mergeRecipes sums the counts of every recipe, the unique recipe count is the number of recipes summed
+func (m *reportMerger) mergeRecipes() {
+	for _, report := range m.reports {
+		for _, recipeCount := range report.CountPerRecipe {
+			m.recipes[recipeCount.Recipe] += recipeCount.RecipeCount
+		}
+	}
+
+	hasRecipes := m.all(model.SectionCountPerRecipe)
+	if hasRecipes {
+		m.merged.SetCountPerRecipe(m.recipes.recipeCounts())
+	}
+	if m.all(model.SectionUniqueRecipeCount) {
+		if !hasRecipes {
+			m.warnf("leaving out section %s, it is recomputed from %s", model.SectionUniqueRecipeCount,
+				model.SectionCountPerRecipe)
+			m.merged.Omit(model.SectionUniqueRecipeCount)
+			return
+		}
+		m.merged.SetUniqueRecipeCount(len(m.recipes))
+	}
+}
+
+// mergeMatches merges the recipes matched by name and by term, the deliveries of a term are recomputed from the
+// summed recipe counts
+func (m *reportMerger) mergeMatches() {
+	if !m.all(model.SectionMatchByName) {
+		return
+	}
+
+	matched := make(map[string]bool)
+	for _, report := range m.reports {
+		for _, name := range report.MatchByName {
+			matched[name] = true
+		}
+	}
+	matches := make(model.RecipeMatches, 0, len(matched))
+	for name := range matched {
+		matches = append(matches, name)
+	}
+	sort.Strings(matches)
+	m.merged.SetMatchByName(matches)
+
+	for _, report := range m.reports {
+		if report.MatchByTerm == nil {
+			return
+		}
+	}
+
+	// terms keep the order of the first report, terms of the other reports follow in the order they appear
+	var terms []string
+	termRecipes := make(map[string]map[string]model.MatchedRecipe)
+	for _, report := range m.reports {
+		for _, termMatch := range report.MatchByTerm {
+			recipes, ok := termRecipes[termMatch.Term]
+			if !ok {
+				recipes = make(map[string]model.MatchedRecipe)
+				termRecipes[termMatch.Term] = recipes
+				terms = append(terms, termMatch.Term)
+			}
+			for _, recipe := range termMatch.Recipes {
+				recipes[recipe.Recipe] = recipe
+			}
+		}
+	}
+
+	termMatches := make([]model.TermMatch, 0, len(terms))
+	unmatchedTerms := make([]string, 0)
+	for _, term := range terms {
+		termMatch := model.TermMatch{Term: term, Recipes: make([]model.MatchedRecipe, 0, len(termRecipes[term]))}
+		for _, recipe := range termRecipes[term] {
+			recipe.RecipeCount = m.recipes[recipe.Recipe]
+			termMatch.Recipes = append(termMatch.Recipes, recipe)
+			termMatch.DeliveryCount += recipe.RecipeCount
+		}
+		sort.Slice(termMatch.Recipes, func(i, j int) bool {
+			return termMatch.Recipes[i].Recipe < termMatch.Recipes[j].Recipe
+		})
+		if len(termMatch.Recipes) == 0 {
+			unmatchedTerms = append(unmatchedTerms, term)
+		}
+		termMatches = append(termMatches, termMatch)
+	}
+	m.merged.SetMatchByTerm(termMatches, unmatchedTerms)
+}
+
+// mergePostcodes sums the postcode counts and ranks the busiest and top postcodes from them. reports of inputs without
+// any delivery may leave the postcode counts out, which can only be told from the data quality section. a report
+// without postcode counts nor data quality is taken to have had deliveries
+func (m *reportMerger) mergePostcodes() {
+	pa := &PostcodeAggregator{postcodeMap: make(postcodeMap)}
+	var hasPostcodeCounts, missingPostcodeCounts bool
+	for _, report := range m.reports {
+		pa.topPostcodes = maxInt(pa.topPostcodes, len(report.TopPostcodes))
+		if report.PostcodeCounts == nil &&
+			(report.Omitted(model.SectionDataQuality) || report.DataQuality.RecordsAccepted > 0) {
+			missingPostcodeCounts = true
+			continue
+		}
+		hasPostcodeCounts = hasPostcodeCounts || report.PostcodeCounts != nil
+		for _, postcodeCount := range report.PostcodeCounts {
+			pa.postcodeMap[postcodeCount.Postcode] += postcodeCount.DeliveryCount
+		}
+	}
+
+	ranked := m.all(model.SectionBusiestPostcode) || pa.topPostcodes > 0
+	if missingPostcodeCounts {
+		if !ranked {
+			return
+		}
+		m.warnf("leaving out sections %s and %s, they can only be merged from %s (--postcode-counts)",
+			model.SectionBusiestPostcode, model.SectionTopPostcodes, model.SectionPostcodeCounts)
+		m.merged.Omit(model.SectionBusiestPostcode)
+		return
+	}
+
+	if !m.merged.Omitted(model.SectionBusiestPostcode) {
+		m.merged.SetBusiestPostcode(pa.GetBusiestPostcode())
+	}
+	m.merged.SetTopPostcodes(pa.GetTopPostcodes())
+	if hasPostcodeCounts {
+		m.merged.SetPostcodeCounts(pa.GetPostcodeCounts())
+	}
+}
+
+// postcodeTimeKey tells the postcode/time-window counts apart
+func postcodeTimeKey(postcodeTimeCount model.PostcodeTimeCount) string {
+	modes := make([]string, 0, len(postcodeTimeCount.Matches))
+	for _, match := range postcodeTimeCount.Matches {
+		modes = append(modes, string(match.Mode))
+	}
+	return strings.Join([]string{postcodeTimeCount.Postcode, postcodeTimeCount.From, postcodeTimeCount.To,
+		strings.Join(postcodeTimeCount.Days, ","), strings.Join(modes, ",")}, "|")
+}
+
+// mergePostcodeTimeCounts sums the postcode/time-window counts, which have to be the same queries matched with the
+// same match modes in every report
+func (m *reportMerger) mergePostcodeTimeCounts() error {
+	if !m.all(model.SectionCountPerPostcodeAndTime) {
+		return nil
+	}
+
+	first := m.reports[0]
+	singleQuery := first.SingleQueryCompat()
+	merged := make([]model.PostcodeTimeCount, 0, len(first.CountPerPostcodeAndTime))
+	for _, postcodeTimeCount := range first.CountPerPostcodeAndTime {
+		postcodeTimeCount.Matches = append([]model.WindowMatch(nil), postcodeTimeCount.Matches...)
+		merged = append(merged, postcodeTimeCount)
+	}
+
+	for i, report := range m.reports[1:] {
+		if len(report.CountPerPostcodeAndTime) != len(merged) {
+			return fmt.Errorf("%w: report %d has %d postcode counts, report 1 has %d", ErrUnmergeableReports, i+2,
+				len(report.CountPerPostcodeAndTime), len(merged))
+		}
+		singleQuery = singleQuery && report.SingleQueryCompat()
+
+		for j, postcodeTimeCount := range report.CountPerPostcodeAndTime {
+			if postcodeTimeKey(postcodeTimeCount) != postcodeTimeKey(merged[j]) {
+				return fmt.Errorf("%w: postcode count %d of report %d (%s %s - %s) is not the one of report 1 "+
+					"(%s %s - %s)", ErrUnmergeableReports, j+1, i+2, postcodeTimeCount.Postcode, postcodeTimeCount.From,
+					postcodeTimeCount.To, merged[j].Postcode, merged[j].From, merged[j].To)
+			}
+			merged[j].DeliveryCount += postcodeTimeCount.DeliveryCount
+			for k, match := range postcodeTimeCount.Matches {
+				merged[j].Matches[k].DeliveryCount += match.DeliveryCount
+				merged[j].Matches[k].OverlapMinutes += match.OverlapMinutes
+			}
+		}
+	}
+
+	m.merged.SetCountPerPostcodeAndTime(merged)
+	m.merged.SetSingleQueryCompat(singleQuery)
+	return nil
+}
+
+// mergeWeekdays sums the deliveries and recipe counts of every weekday, when every report has them
+func (m *reportMerger) mergeWeekdays() {
+	if !m.every(model.SectionCountPerWeekday, func(report *model.ReportModel) bool {
+		return len(report.CountPerWeekday) > 0
+	}) {
+		return
+	}
+
+	wa := newWeekdayAggregator()
+	index := make(map[string]int, len(model.Weekdays))
+	for _, weekday := range model.Weekdays {
+		index[weekday.String()] = int(weekday)
+	}
+
+	for _, report := range m.reports {
+		for _, weekdayCount := range report.CountPerWeekday {
+			weekday := index[weekdayCount.Weekday]
+			wa.deliveries[weekday] += weekdayCount.DeliveryCount
+			for _, recipeCount := range weekdayCount.CountPerRecipe {
+				wa.recipes[weekday][recipeCount.Recipe] += recipeCount.RecipeCount
+			}
+		}
+	}
+	m.merged.SetCountPerWeekday(wa.GetWeekdayCounts())
+}
+
+// mergeHourlyLoad sums the hourly load of all the deliveries and of every weekday, when every report has it. the
+// busiest postcodes of the merged report are not known, their hourly load is left out
+func (m *reportMerger) mergeHourlyLoad() {
+	if !m.every(model.SectionHourlyLoad, func(report *model.ReportModel) bool {
+		return report.HourlyLoad != nil
+	}) {
+		return
+	}
+
+	merged := &model.HourlyLoadReport{
+		HourlyLoad: model.HourlyLoad{Hours: make([]int, model.HoursPerDay)},
+		Postcodes:  []model.PostcodeHourlyLoad{},
+	}
+	weekdays := make(map[string]int)
+	var hasPostcodes bool
+	for _, report := range m.reports {
+		hasPostcodes = hasPostcodes || len(report.HourlyLoad.Postcodes) > 0
+
+		addHours(merged.Hours, report.HourlyLoad.Hours)
+		for _, weekdayLoad := range report.HourlyLoad.ByWeekday {
+			i, ok := weekdays[weekdayLoad.Weekday]
+			if !ok {
+				i = len(merged.ByWeekday)
+				weekdays[weekdayLoad.Weekday] = i
+				merged.ByWeekday = append(merged.ByWeekday, model.WeekdayHourlyLoad{
+					Weekday: weekdayLoad.Weekday, Hours: make([]int, model.HoursPerDay)})
+			}
+			addHours(merged.ByWeekday[i].Hours, weekdayLoad.Hours)
+		}
+	}
+	if hasPostcodes {
+		m.warnf("leaving out the hourly load of the busiest postcodes, it cannot be merged")
+	}
+	m.merged.SetHourlyLoad(merged)
+}
+
+// addHours adds the counts of hours to sum
+func addHours(sum, hours []int) {
+	for i := 0; i < len(sum) && i < len(hours); i++ {
+		sum[i] += hours[i]
+	}
+}
+
+// mergeDataQuality sums the rejected records, the records read and accepted and the rejections of every reason. the
+// distinct samples of a reason are kept up to the most samples any of the reports has for it. the data quality is
+// left out when any of the reports does not have it
+func (m *reportMerger) mergeDataQuality() {
+	hasDataQuality := m.all(model.SectionDataQuality)
+
+	var dataQuality model.DataQuality
+	rejections := make(map[model.RejectionReason]*model.Rejection)
+	samplesCap := make(map[model.RejectionReason]int)
+
+	for _, report := range m.reports {
+		m.merged.SetRejectedCount(m.merged.RejectedCount + report.RejectedCount)
+		m.merged.SetPerFile(append(m.merged.PerFile, report.PerFile...))
+		m.merged.SetIncomplete(m.merged.Incomplete || report.Incomplete)
+
+		dataQuality.RecordsRead += report.DataQuality.RecordsRead
+		dataQuality.RecordsAccepted += report.DataQuality.RecordsAccepted
+		for _, rejection := range report.DataQuality.Rejections {
+			merged, ok := rejections[rejection.Reason]
+			if !ok {
+				merged = &model.Rejection{Reason: rejection.Reason, Samples: []string{}}
+				rejections[rejection.Reason] = merged
+			}
+			merged.Count += rejection.Count
+			samplesCap[rejection.Reason] = maxInt(samplesCap[rejection.Reason], len(rejection.Samples))
+			for _, sample := range rejection.Samples {
+				if len(merged.Samples) < samplesCap[rejection.Reason] && !containsString(merged.Samples, sample) {
+					merged.Samples = append(merged.Samples, sample)
+				}
+			}
+		}
+	}
+
+	if hasDataQuality {
+		dataQuality.Rejections = make([]model.Rejection, 0, len(rejections))
+		for _, reason := range model.RejectionReasons {
+			if rejection, ok := rejections[reason]; ok {
+				dataQuality.Rejections = append(dataQuality.Rejections, *rejection)
+			}
+		}
+		m.merged.SetDataQuality(dataQuality)
+	}
+
+	sort.Slice(m.merged.PerFile, func(i, j int) bool {
+		return m.merged.PerFile[i].File < m.merged.PerFile[j].File
+	})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func maxInt(values ...int) int {
+	max := values[0]
+	for _, v := range values[1:] {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
@@ -0,0 +1,222 @@
+package aggregate
+
+import (
+	"testing"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockPartitionReports() []*model.ReportModel {
+	first := model.NewReportModel()
+	first.SetUniqueRecipeCount(2)
+	first.SetCountPerRecipe(model.RecipeCounts{{Recipe: "Pear", RecipeCount: 1}, {Recipe: "Steak", RecipeCount: 2}})
+	first.SetBusiestPostcode(model.PostcodeCount{Postcode: "10120", DeliveryCount: 2})
+	first.SetTopPostcodes([]model.RankedPostcodeCount{{Rank: 1, Postcode: "10120", DeliveryCount: 2, Share: 66.67}})
+	first.SetPostcodeCounts([]model.PostcodeCount{
+		{Postcode: "10120", DeliveryCount: 2},
+		{Postcode: "10245", DeliveryCount: 1},
+	})
+	first.SetCountPerPostcodeAndTime([]model.PostcodeTimeCount{
+		{Postcode: "10120", From: "10AM", To: "3PM", DeliveryCount: 1,
+			Matches: []model.WindowMatch{{Mode: model.MatchContained, DeliveryCount: 1, OverlapMinutes: 60}}},
+	})
+	first.SetMatchByName(model.RecipeMatches{"Pear"})
+	first.SetMatchByTerm([]model.TermMatch{
+		{Term: "ea", DeliveryCount: 1, Recipes: []model.MatchedRecipe{{Recipe: "Pear", RecipeCount: 1}}},
+		{Term: "Potato", Recipes: []model.MatchedRecipe{}},
+	}, []string{"Potato"})
+	first.SetRejectedCount(1)
+	first.SetDataQuality(model.DataQuality{RecordsRead: 4, RecordsAccepted: 3, Rejections: []model.Rejection{
+		{Reason: model.RejectionMissingField, Count: 1, Samples: []string{"postcode"}},
+	}})
+
+	second := model.NewReportModel()
+	second.SetUniqueRecipeCount(2)
+	second.SetCountPerRecipe(model.RecipeCounts{{Recipe: "Apple", RecipeCount: 1}, {Recipe: "Steak", RecipeCount: 1}})
+	second.SetBusiestPostcode(model.PostcodeCount{Postcode: "10245", DeliveryCount: 2})
+	second.SetTopPostcodes([]model.RankedPostcodeCount{{Rank: 1, Postcode: "10245", DeliveryCount: 2, Share: 100}})
+	second.SetPostcodeCounts([]model.PostcodeCount{{Postcode: "10245", DeliveryCount: 2}})
+	second.SetCountPerPostcodeAndTime([]model.PostcodeTimeCount{
+		{Postcode: "10120", From: "10AM", To: "3PM", DeliveryCount: 0,
+			Matches: []model.WindowMatch{{Mode: model.MatchContained}}},
+	})
+	second.SetMatchByName(model.RecipeMatches{"Steak"})
+	second.SetMatchByTerm([]model.TermMatch{
+		{Term: "ea", DeliveryCount: 1, Recipes: []model.MatchedRecipe{{Recipe: "Steak", RecipeCount: 1}}},
+		{Term: "Potato", Recipes: []model.MatchedRecipe{}},
+	}, []string{"Potato"})
+	second.SetDataQuality(model.DataQuality{RecordsRead: 2, RecordsAccepted: 2, Rejections: []model.Rejection{
+		{Reason: model.RejectionMissingField, Count: 0, Samples: []string{}},
+	}})
+
+	return []*model.ReportModel{first, second}
+}
+
+func TestMergeReports(t *testing.T) {
+	got, warnings, err := MergeReports(mockPartitionReports()...)
+	assert.Nil(t, err)
+	assert.Empty(t, warnings)
+
+	want := model.NewReportModel()
+	want.SetUniqueRecipeCount(3)
+	want.SetCountPerRecipe(model.RecipeCounts{
+		{Recipe: "Apple", RecipeCount: 1},
+		{Recipe: "Pear", RecipeCount: 1},
+		{Recipe: "Steak", RecipeCount: 3},
+	})
+	// 10245 has the most deliveries once merged, although it was not the busiest postcode of the first report
+	want.SetBusiestPostcode(model.PostcodeCount{Postcode: "10245", DeliveryCount: 3})
+	want.SetTopPostcodes([]model.RankedPostcodeCount{{Rank: 1, Postcode: "10245", DeliveryCount: 3, Share: 60}})
+	want.SetPostcodeCounts([]model.PostcodeCount{
+		{Postcode: "10120", DeliveryCount: 2},
+		{Postcode: "10245", DeliveryCount: 3},
+	})
+	want.SetCountPerPostcodeAndTime([]model.PostcodeTimeCount{
+		{Postcode: "10120", From: "10AM", To: "3PM", DeliveryCount: 1,
+			Matches: []model.WindowMatch{{Mode: model.MatchContained, DeliveryCount: 1, OverlapMinutes: 60}}},
+	})
+	want.SetMatchByName(model.RecipeMatches{"Pear", "Steak"})
+	want.SetMatchByTerm([]model.TermMatch{
+		{Term: "ea", DeliveryCount: 4, Recipes: []model.MatchedRecipe{
+			{Recipe: "Pear", RecipeCount: 1},
+			{Recipe: "Steak", RecipeCount: 3},
+		}},
+		{Term: "Potato", Recipes: []model.MatchedRecipe{}},
+	}, []string{"Potato"})
+	want.SetRejectedCount(1)
+	want.SetDataQuality(model.DataQuality{RecordsRead: 6, RecordsAccepted: 5, Rejections: []model.Rejection{
+		{Reason: model.RejectionMissingField, Count: 1, Samples: []string{"postcode"}},
+	}})
+
+	assert.Equal(t, want, got)
+}
+
+func TestMergeReports_WithoutPostcodeCounts(t *testing.T) {
+	reports := mockPartitionReports()
+	reports[1].SetPostcodeCounts(nil)
+
+	got, _, err := MergeReports(reports...)
+	assert.Nil(t, err)
+
+	assert.True(t, got.Omitted(model.SectionBusiestPostcode))
+	assert.Nil(t, got.TopPostcodes)
+	assert.Nil(t, got.PostcodeCounts)
+	assert.Equal(t, 3, got.UniqueRecipeCount)
+}
+
+func TestMergeReports_WithoutDataQuality(t *testing.T) {
+	// without its data quality the report cannot be told apart from the one of an input without deliveries
+	reports := mockPartitionReports()
+	reports[1].SetPostcodeCounts(nil)
+	reports[1].SetDataQuality(model.DataQuality{})
+	reports[1].Omit(model.SectionDataQuality)
+
+	got, warnings, err := MergeReports(reports...)
+	assert.Nil(t, err)
+
+	assert.True(t, got.Omitted(model.SectionBusiestPostcode))
+	assert.Nil(t, got.PostcodeCounts)
+	assert.True(t, got.Omitted(model.SectionDataQuality))
+	assert.Equal(t, []string{
+		"leaving out sections busiest_postcode and top_postcodes, they can only be merged from postcode_counts " +
+			"(--postcode-counts)",
+		"leaving out section data_quality, 1 of the reports do not have it",
+	}, warnings)
+}
+
+func TestMergeReports_OptionalSections(t *testing.T) {
+	reports := mockPartitionReports()
+	hours := func(hour, count int) []int {
+		load := make([]int, model.HoursPerDay)
+		load[hour] = count
+		return load
+	}
+	for i, report := range reports {
+		report.SetCountPerWeekday([]model.WeekdayCount{
+			{Weekday: "Monday", DeliveryCount: 2, UniqueRecipeCount: 1,
+				CountPerRecipe: model.RecipeCounts{{Recipe: "Steak", RecipeCount: 2}}},
+			{Weekday: "Tuesday", DeliveryCount: i, UniqueRecipeCount: i,
+				CountPerRecipe: model.RecipeCounts{{Recipe: "Pear", RecipeCount: i}}},
+		})
+		report.SetHourlyLoad(&model.HourlyLoadReport{
+			HourlyLoad: model.HourlyLoad{
+				Hours:     hours(10, i+1),
+				ByWeekday: []model.WeekdayHourlyLoad{{Weekday: "Monday", Hours: hours(10, i+1)}},
+			},
+			Postcodes: []model.PostcodeHourlyLoad{{Postcode: "10120", DeliveryCount: 1}},
+		})
+		report.SetSection("recipe_name_length", 12)
+	}
+
+	got, warnings, err := MergeReports(reports...)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"leaving out the hourly load of the busiest postcodes, it cannot be merged",
+		"leaving out custom section recipe_name_length, it cannot be merged",
+		"leaving out custom section recipe_name_length, it cannot be merged",
+	}, warnings)
+
+	assert.Len(t, got.CountPerWeekday, len(model.Weekdays))
+	assert.Equal(t, model.WeekdayCount{Weekday: "Monday", DeliveryCount: 4, UniqueRecipeCount: 1,
+		CountPerRecipe: model.RecipeCounts{{Recipe: "Steak", RecipeCount: 4}}}, got.CountPerWeekday[0])
+	assert.Equal(t, model.WeekdayCount{Weekday: "Tuesday", DeliveryCount: 1, UniqueRecipeCount: 1,
+		CountPerRecipe: model.RecipeCounts{{Recipe: "Pear", RecipeCount: 1}}}, got.CountPerWeekday[1])
+
+	// the hourly load of the busiest postcodes and the custom sections cannot be merged
+	assert.Equal(t, &model.HourlyLoadReport{
+		HourlyLoad: model.HourlyLoad{
+			Hours:     hours(10, 3),
+			ByWeekday: []model.WeekdayHourlyLoad{{Weekday: "Monday", Hours: hours(10, 3)}},
+		},
+		Postcodes: []model.PostcodeHourlyLoad{},
+	}, got.HourlyLoad)
+	assert.Empty(t, got.CustomSections())
+
+	reports[1].SetHourlyLoad(nil)
+	got, _, err = MergeReports(reports...)
+	assert.Nil(t, err)
+	assert.Nil(t, got.HourlyLoad)
+}
+
+func TestMergeReports_Unmergeable(t *testing.T) {
+	tcs := []struct {
+		name   string
+		modify func(reports []*model.ReportModel) []*model.ReportModel
+	}{
+		{
+			name: "no reports",
+			modify: func(reports []*model.ReportModel) []*model.ReportModel {
+				return nil
+			},
+		},
+		{
+			name: "different number of postcode counts",
+			modify: func(reports []*model.ReportModel) []*model.ReportModel {
+				reports[1].SetCountPerPostcodeAndTime(nil)
+				return reports
+			},
+		},
+		{
+			name: "different time window",
+			modify: func(reports []*model.ReportModel) []*model.ReportModel {
+				reports[1].CountPerPostcodeAndTime[0].To = "4PM"
+				return reports
+			},
+		},
+		{
+			name: "different match modes",
+			modify: func(reports []*model.ReportModel) []*model.ReportModel {
+				reports[1].CountPerPostcodeAndTime[0].Matches[0].Mode = model.MatchOverlaps
+				return reports
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := MergeReports(tc.modify(mockPartitionReports())...)
+			assert.ErrorIs(t, err, ErrUnmergeableReports)
+		})
+	}
+}
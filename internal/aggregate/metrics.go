@@ -0,0 +1,225 @@
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+)
+
+// DefaultTopPostcodes is the number of postcodes ranked when the top postcodes metric is selected without a number
+const DefaultTopPostcodes = 10
+
+var ErrUnknownMetric = errors.New("unknown metric")
+
+// Metric aggregates the recipes into a named section of the report. Aggregate is called for every recipe from a
+// single goroutine, Finalize once all the recipes were aggregated and Contribute once finalized. built-in metrics set
+// the fields of the report, custom metrics add their section with model.ReportModel.SetSection
+type Metric interface {
+	Name() string
+	Aggregate(recipe *model.Recipe)
+	Finalize()
+	Contribute(report *model.ReportModel)
+}
+
+// MetricFactory returns a new Metric configured by aggrInput
+type MetricFactory func(aggrInput *AggregatorInput) Metric
+
+// DefaultMetrics are the metrics of the functional requirements, computed when no metrics are selected
+var DefaultMetrics = []string{
+	model.SectionUniqueRecipeCount,
+	model.SectionCountPerRecipe,
+	model.SectionBusiestPostcode,
+	model.SectionCountPerPostcodeAndTime,
+	model.SectionMatchByName,
+}
+
+// registry holds the factory of every registered metric by name
+var registry = make(map[string]MetricFactory)
+
+func init() {
+	register(model.SectionUniqueRecipeCount, func(aggrInput *AggregatorInput) Metric {
+		m, ra := aggrInput.sharedAggregators().recipeMetric(aggrInput)
+		m.name = model.SectionUniqueRecipeCount
+		m.contribute = func(report *model.ReportModel) {
+			report.SetUniqueRecipeCount(ra.GetUniqueRecipeCount())
+		}
+		return m
+	})
+	register(model.SectionCountPerRecipe, func(aggrInput *AggregatorInput) Metric {
+		m, ra := aggrInput.sharedAggregators().recipeMetric(aggrInput)
+		m.name = model.SectionCountPerRecipe
+		m.contribute = func(report *model.ReportModel) {
+			report.SetCountPerRecipe(ra.GetRecipeCountsModel())
+		}
+		return m
+	})
+	register(model.SectionMatchByName, func(aggrInput *AggregatorInput) Metric {
+		m, ra := aggrInput.sharedAggregators().recipeMetric(aggrInput)
+		m.name = model.SectionMatchByName
+		m.contribute = func(report *model.ReportModel) {
+			report.SetMatchByName(ra.GetRecipeMatches())
+			report.SetMatchByTerm(ra.GetTermMatches())
+		}
+		return m
+	})
+	register(model.SectionBusiestPostcode, func(aggrInput *AggregatorInput) Metric {
+		m, pa := aggrInput.sharedAggregators().postcodeMetric(aggrInput)
+		m.name = model.SectionBusiestPostcode
+		m.contribute = func(report *model.ReportModel) {
+			report.SetBusiestPostcode(pa.GetBusiestPostcode())
+		}
+		return m
+	})
+	register(model.SectionTopPostcodes, func(aggrInput *AggregatorInput) Metric {
+		m, pa := aggrInput.sharedAggregators().postcodeMetric(aggrInput)
+		m.name = model.SectionTopPostcodes
+		m.contribute = func(report *model.ReportModel) {
+			report.SetTopPostcodes(pa.GetTopPostcodes())
+		}
+		return m
+	})
+	register(model.SectionPostcodeCounts, func(aggrInput *AggregatorInput) Metric {
+		m, pa := aggrInput.sharedAggregators().postcodeMetric(aggrInput)
+		m.name = model.SectionPostcodeCounts
+		m.contribute = func(report *model.ReportModel) {
+			report.SetPostcodeCounts(pa.GetPostcodeCounts())
+		}
+		return m
+	})
+	register(model.SectionCountPerPostcodeAndTime, func(aggrInput *AggregatorInput) Metric {
+		pa := newPostcodeQueryAggregator(aggrInput)
+		return &metric{
+			name:      model.SectionCountPerPostcodeAndTime,
+			aggregate: pa.matchQueries,
+			contribute: func(report *model.ReportModel) {
+				report.SetCountPerPostcodeAndTime(pa.GetPostcodeTimeCounts())
+			},
+		}
+	})
+	register(model.SectionCountPerWeekday, func(aggrInput *AggregatorInput) Metric {
+		wa := newWeekdayAggregator()
+		return &metric{
+			name:      model.SectionCountPerWeekday,
+			aggregate: wa.aggregate,
+			contribute: func(report *model.ReportModel) {
+				report.SetCountPerWeekday(wa.GetWeekdayCounts())
+			},
+		}
+	})
+	register(model.SectionHourlyLoad, func(aggrInput *AggregatorInput) Metric {
+		ha := newHourlyAggregator(aggrInput)
+		return &metric{
+			name:      model.SectionHourlyLoad,
+			aggregate: ha.aggregate,
+			contribute: func(report *model.ReportModel) {
+				report.SetHourlyLoad(ha.GetHourlyLoad())
+			},
+		}
+	})
+}
+
+// Register makes a custom metric available under name, which is also the name of the report section it contributes.
+// it is meant to be called from an init function and panics when the name is taken by another metric or by a
+// built-in section of the report
+func Register(name string, factory MetricFactory) {
+	if model.IsBuiltInSection(name) {
+		panic(fmt.Sprintf("metric %s would replace a built-in report section", name))
+	}
+	register(name, factory)
+}
+
+func register(name string, factory MetricFactory) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("metric %s is already registered", name))
+	}
+	registry[name] = factory
+}
+
+// RegisteredMetrics returns the names of all the registered metrics in ascending order
+func RegisteredMetrics() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsRegisteredMetric returns whether a metric is registered under name
+func IsRegisteredMetric(name string) bool {
+	_, ok := registry[name]
+	return ok
+}
+
+// NewMetric returns a new instance of the metric registered under name
+func NewMetric(name string, aggrInput *AggregatorInput) (Metric, error) {
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMetric, name)
+	}
+	return factory(aggrInput), nil
+}
+
+// metric adapts the built-in aggregators to Metric. the metrics reading a shared aggregator without feeding it have no
+// aggregate and finalize funcs
+type metric struct {
+	name       string
+	aggregate  func(recipe *model.Recipe)
+	finalize   func()
+	contribute func(report *model.ReportModel)
+}
+
+func (m *metric) Name() string {
+	return m.name
+}
+
+func (m *metric) Aggregate(recipe *model.Recipe) {
+	if m.aggregate != nil {
+		m.aggregate(recipe)
+	}
+}
+
+func (m *metric) Finalize() {
+	if m.finalize != nil {
+		m.finalize()
+	}
+}
+
+func (m *metric) Contribute(report *model.ReportModel) {
+	m.contribute(report)
+}
+
+// sharedAggregators holds the recipe and the postcode aggregator the built-in metrics of a run read their sections
+// from, so that every recipe is counted once whatever the number of metrics selected. an aggregator is created by the
+// first metric needing it, which is also the one feeding it the recipes
+type sharedAggregators struct {
+	recipes   *RecipeAggregator
+	postcodes *PostcodeAggregator
+}
+
+// recipeMetric returns a metric reading from the shared recipe aggregator, which the metric feeds when it created it
+func (s *sharedAggregators) recipeMetric(aggrInput *AggregatorInput) (*metric, *RecipeAggregator) {
+	if s.recipes != nil {
+		return &metric{}, s.recipes
+	}
+
+	s.recipes = newRecipeAggregator(aggrInput)
+	return &metric{aggregate: s.recipes.aggregate, finalize: s.recipes.postAggregate}, s.recipes
+}
+
+// postcodeMetric returns a metric reading from the shared postcode aggregator, which the metric feeds when it created
+// it. the aggregator ranks the TopPostcodes of aggrInput, or the DefaultTopPostcodes when not set
+func (s *sharedAggregators) postcodeMetric(aggrInput *AggregatorInput) (*metric, *PostcodeAggregator) {
+	if s.postcodes != nil {
+		return &metric{}, s.postcodes
+	}
+
+	topPostcodes := aggrInput.TopPostcodes
+	if topPostcodes <= 0 {
+		topPostcodes = DefaultTopPostcodes
+	}
+	s.postcodes = newPostcodeAggregator(&AggregatorInput{TopPostcodes: topPostcodes})
+	return &metric{aggregate: s.postcodes.count}, s.postcodes
+}
@@ -0,0 +1,121 @@
+package aggregate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/davido912-recipe-count-test-2020/internal/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// recipeLengthMetric is a custom metric summing up the length of the recipe names
+type recipeLengthMetric struct {
+	total     int
+	finalized bool
+}
+
+func (m *recipeLengthMetric) Name() string {
+	return "recipe_name_length"
+}
+
+func (m *recipeLengthMetric) Aggregate(recipe *model.Recipe) {
+	m.total += len(recipe.Recipe)
+}
+
+func (m *recipeLengthMetric) Finalize() {
+	m.finalized = true
+}
+
+func (m *recipeLengthMetric) Contribute(report *model.ReportModel) {
+	report.SetSection(m.Name(), m.total)
+}
+
+func TestRegister(t *testing.T) {
+	custom := &recipeLengthMetric{}
+	Register(custom.Name(), func(_ *AggregatorInput) Metric {
+		return custom
+	})
+	defer delete(registry, custom.Name())
+
+	assert.True(t, IsRegisteredMetric(custom.Name()))
+	assert.Contains(t, RegisteredMetrics(), custom.Name())
+
+	assert.Panics(t, func() {
+		Register(custom.Name(), func(_ *AggregatorInput) Metric { return custom })
+	}, "registering a name twice")
+	assert.Panics(t, func() {
+		Register(model.SectionDataQuality, func(_ *AggregatorInput) Metric { return custom })
+	}, "registering the name of a built-in section")
+
+	recipes := testutils.MockRecipes()
+	recipeChan := make(chan *model.Recipe, len(recipes))
+	var want int
+	for _, recipe := range recipes {
+		recipeChan <- recipe
+		want += len(recipe.Recipe)
+	}
+	close(recipeChan)
+
+	aggr := NewAggregator(&AggregatorInput{Metrics: []string{model.SectionUniqueRecipeCount, custom.Name()}})
+	aggr.Aggregate(context.Background(), recipeChan)
+	assert.True(t, custom.finalized)
+
+	report := model.NewReportModel()
+	aggr.Contribute(report)
+
+	got, ok := report.Section(custom.Name())
+	assert.True(t, ok)
+	assert.Equal(t, want, got)
+	assert.Equal(t, 5, report.UniqueRecipeCount)
+}
+
+func TestNewMetric(t *testing.T) {
+	for _, name := range RegisteredMetrics() {
+		m, err := NewMetric(name, &AggregatorInput{})
+		assert.Nil(t, err)
+		assert.Equal(t, name, m.Name())
+	}
+
+	_, err := NewMetric("unknown", &AggregatorInput{})
+	assert.ErrorIs(t, err, ErrUnknownMetric)
+}
+
+func TestAggregatorInput_metricNames(t *testing.T) {
+	tcs := []struct {
+		name      string
+		aggrInput AggregatorInput
+		want      []string
+	}{
+		{
+			name:      "default metrics",
+			aggrInput: AggregatorInput{},
+			want:      DefaultMetrics,
+		},
+		{
+			name:      "selected metrics",
+			aggrInput: AggregatorInput{Metrics: []string{model.SectionBusiestPostcode}},
+			want:      []string{model.SectionBusiestPostcode},
+		},
+		{
+			name: "optional metrics enabled by their options",
+			aggrInput: AggregatorInput{
+				Metrics:        []string{model.SectionBusiestPostcode, model.SectionTopPostcodes},
+				TopPostcodes:   3,
+				PostcodeCounts: true,
+				Weekdays:       true,
+				HourlyLoad:     true,
+			},
+			want: []string{
+				model.SectionBusiestPostcode, model.SectionTopPostcodes, model.SectionPostcodeCounts,
+				model.SectionCountPerWeekday, model.SectionHourlyLoad,
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.aggrInput.metricNames())
+		})
+	}
+}
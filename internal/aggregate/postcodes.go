@@ -1,8 +1,12 @@
 package aggregate
 
 import (
+	"errors"
 	"fmt"
 	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"math"
+	"sort"
+	"time"
 )
 
 const (
@@ -10,19 +14,90 @@ const (
 	DistinctPostcodesCap  = 1_000_000
 )
 
+var ErrPostcodeTooLong = errors.New("postcode too long")
+
 type postcodeMap map[string]int
 
+// PostcodeQuery counts the deliveries to Postcode happening within the DeliveryFrom - DeliveryTo timespan of any
+// weekday, or only of the weekdays in Days when set. a timespan whose end is before its start crosses midnight, e.g.
+// 8PM - 2AM on Friday ends on Saturday 2AM
+type PostcodeQuery struct {
+	Postcode                 string
+	DeliveryFrom, DeliveryTo *model.DeliveryTime
+	Days                     []time.Weekday
+}
+
 type PostcodeAggregator struct {
 	postcodeMap
-	postcodeTimeCount model.PostcodeTimeCount
-	aggrDeliveryFrom  *model.DeliveryTime
-	aggrDeliveryTo    *model.DeliveryTime
+
+	// postcodeTimeCounts holds the result of every query in postcodeQueries at the same index. every query is
+	// matched with all the matchModes, the first mode decides the delivery count of the query
+	postcodeQueries    []PostcodeQuery
+	postcodeTimeCounts []model.PostcodeTimeCount
+	matchModes         []model.MatchMode
+	topPostcodes       int
+}
+
+// newPostcodeAggregator returns a PostcodeAggregator counting the deliveries per postcode and evaluating all the
+// queries in a single pass over the recipes
+func newPostcodeAggregator(aggrInput *AggregatorInput) *PostcodeAggregator {
+	pa := newPostcodeQueryAggregator(aggrInput)
+	pa.postcodeMap = make(postcodeMap, DistinctPostcodesCap)
+	pa.topPostcodes = aggrInput.TopPostcodes
+	return pa
+}
+
+// newPostcodeQueryAggregator returns a PostcodeAggregator that only evaluates the queries, see matchQueries. the times
+// of the queries are rendered in the time format of aggrInput. deliveries are matched with the contained mode unless
+// match modes are passed, in which case the result of every mode is detailed per query
+func newPostcodeQueryAggregator(aggrInput *AggregatorInput) *PostcodeAggregator {
+	matchModes := aggrInput.MatchModes
+	if len(matchModes) == 0 {
+		matchModes = []model.MatchMode{model.MatchContained}
+	}
+
+	postcodeTimeCounts := make([]model.PostcodeTimeCount, 0, len(aggrInput.Queries))
+	for _, query := range aggrInput.Queries {
+		var days []string
+		for _, day := range query.Days {
+			days = append(days, day.String())
+		}
+
+		var matches []model.WindowMatch
+		for _, mode := range aggrInput.MatchModes {
+			matches = append(matches, model.WindowMatch{Mode: mode})
+		}
+
+		postcodeTimeCounts = append(postcodeTimeCounts, model.PostcodeTimeCount{
+			Postcode:      query.Postcode,
+			From:          query.DeliveryFrom.Format(aggrInput.TimeFormat),
+			To:            query.DeliveryTo.Format(aggrInput.TimeFormat),
+			Days:          days,
+			DeliveryCount: 0,
+			Matches:       matches,
+		})
+	}
+
+	return &PostcodeAggregator{
+		postcodeQueries:    aggrInput.Queries,
+		postcodeTimeCounts: postcodeTimeCounts,
+		matchModes:         matchModes,
+	}
+}
+
+// validatePostcode ensures the postcode of the recipe is within the length constraint
+func validatePostcode(recipe *model.Recipe) error {
+	if len(recipe.Postcode) > PostcodeLenConstraint {
+		return fmt.Errorf("%w: postcode: %s is longer than limit: %d",
+			ErrPostcodeTooLong, recipe.Postcode, PostcodeLenConstraint)
+	}
+	return nil
 }
 
 // add adds postcode to map while incrementing its count
 func (pm postcodeMap) add(recipe *model.Recipe) error {
-	if len(recipe.Postcode) > PostcodeLenConstraint {
-		return fmt.Errorf("postcode: %s is longer than limit: %d", recipe.Postcode, PostcodeLenConstraint)
+	if err := validatePostcode(recipe); err != nil {
+		return err
 	}
 	pm[recipe.Postcode]++
 	return nil
@@ -30,16 +105,42 @@ func (pm postcodeMap) add(recipe *model.Recipe) error {
 
 // aggregate aggregates all the relevant data required from recipes + performs checks
 func (pa *PostcodeAggregator) aggregate(recipe *model.Recipe) {
+	pa.count(recipe)
+	pa.matchQueries(recipe)
+}
 
+// count counts the delivery of recipe to its postcode
+func (pa *PostcodeAggregator) count(recipe *model.Recipe) {
 	_ = pa.add(recipe)
+}
 
-	if pa.checkPostcodeEquals(recipe) && pa.checkDeliveryInTimespan(recipe) {
-		pa.incrementPostcodeCount()
-	}
+// matchQueries counts the delivery of recipe for every query it matches
+func (pa *PostcodeAggregator) matchQueries(recipe *model.Recipe) {
+	for i, query := range pa.postcodeQueries {
+		if !query.checkPostcodeEquals(recipe) {
+			continue
+		}
 
+		postcodeTimeCount := &pa.postcodeTimeCounts[i]
+		for j, mode := range pa.matchModes {
+			matched, overlapMinutes := query.match(recipe, mode)
+			if !matched {
+				continue
+			}
+
+			if j == 0 {
+				postcodeTimeCount.DeliveryCount++
+			}
+			if postcodeTimeCount.Matches != nil {
+				postcodeTimeCount.Matches[j].DeliveryCount++
+				postcodeTimeCount.Matches[j].OverlapMinutes += overlapMinutes
+			}
+		}
+	}
 }
 
-// GetBusiestPostcode return a model.PostcodeCount model with the Postcode that has the most events
+// GetBusiestPostcode return a model.PostcodeCount model with the Postcode that has the most events. ties are broken
+// by the lowest postcode so that the result does not depend on the map iteration order
 func (pa *PostcodeAggregator) GetBusiestPostcode() model.PostcodeCount {
 	busiestPostcode := model.PostcodeCount{
 		Postcode:      "n/a",
@@ -47,7 +148,7 @@ func (pa *PostcodeAggregator) GetBusiestPostcode() model.PostcodeCount {
 	}
 
 	for k, v := range pa.postcodeMap {
-		if v > busiestPostcode.DeliveryCount {
+		if rankedBefore(k, v, busiestPostcode.Postcode, busiestPostcode.DeliveryCount) {
 			busiestPostcode = model.PostcodeCount{
 				Postcode:      k,
 				DeliveryCount: v,
@@ -57,24 +158,98 @@ func (pa *PostcodeAggregator) GetBusiestPostcode() model.PostcodeCount {
 	return busiestPostcode
 }
 
-// GetPostcodeTimeCount return a model.PostcodeTimeCount that contains the count of all deliveries happening in the
-// designated postcode during the designated delivery timespan (e.g. 4PM to 8PM)
-func (pa *PostcodeAggregator) GetPostcodeTimeCount() model.PostcodeTimeCount {
-	return pa.postcodeTimeCount
+// GetPostcodeCounts returns the deliveries of every postcode sorted by postcode in ascending order
+func (pa *PostcodeAggregator) GetPostcodeCounts() []model.PostcodeCount {
+	postcodeCounts := make([]model.PostcodeCount, 0, len(pa.postcodeMap))
+	for postcode, count := range pa.postcodeMap {
+		postcodeCounts = append(postcodeCounts, model.PostcodeCount{Postcode: postcode, DeliveryCount: count})
+	}
+	sort.Slice(postcodeCounts, func(i, j int) bool {
+		return postcodeCounts[i].Postcode < postcodeCounts[j].Postcode
+	})
+	return postcodeCounts
+}
+
+// GetTopPostcodes returns the busiest postcodes ranked by their number of deliveries, ties are broken by postcode in
+// ascending order. each postcode carries its share (percentage) of all the deliveries. nil is returned when no
+// ranking was requested
+func (pa *PostcodeAggregator) GetTopPostcodes() []model.RankedPostcodeCount {
+	if pa.topPostcodes <= 0 {
+		return nil
+	}
+
+	var total int
+	postcodes := make([]string, 0, len(pa.postcodeMap))
+	for k, v := range pa.postcodeMap {
+		postcodes = append(postcodes, k)
+		total += v
+	}
+
+	sort.Slice(postcodes, func(i, j int) bool {
+		return rankedBefore(postcodes[i], pa.postcodeMap[postcodes[i]], postcodes[j], pa.postcodeMap[postcodes[j]])
+	})
+	if len(postcodes) > pa.topPostcodes {
+		postcodes = postcodes[:pa.topPostcodes]
+	}
+
+	topPostcodes := make([]model.RankedPostcodeCount, 0, len(postcodes))
+	for i, postcode := range postcodes {
+		count := pa.postcodeMap[postcode]
+		topPostcodes = append(topPostcodes, model.RankedPostcodeCount{
+			Rank:          i + 1,
+			Postcode:      postcode,
+			DeliveryCount: count,
+			Share:         math.Round(float64(count)/float64(total)*100*100) / 100,
+		})
+	}
+	return topPostcodes
+}
+
+// rankedBefore whether a postcode with count deliveries ranks before another postcode: more deliveries rank first,
+// equal deliveries are ranked by postcode in ascending order
+func rankedBefore(postcode string, count int, otherPostcode string, otherCount int) bool {
+	if count != otherCount {
+		return count > otherCount
+	}
+	return postcode < otherPostcode
 }
 
-// checkPostcodeEquals checks whether the postcode passed to the aggregator is equal the postcode of a recipe
-func (pa *PostcodeAggregator) checkPostcodeEquals(recipe *model.Recipe) bool {
-	return pa.postcodeTimeCount.Postcode == recipe.Postcode
+// GetPostcodeTimeCounts return a model.PostcodeTimeCount per query, in the order the queries were passed, that
+// contains the count of all deliveries happening in the designated postcode during the designated delivery timespan
+// (e.g. 4PM to 8PM)
+func (pa *PostcodeAggregator) GetPostcodeTimeCounts() []model.PostcodeTimeCount {
+	return pa.postcodeTimeCounts
 }
 
-// checkDeliveryInTimespan check if recipe delivery timespan is in the timespan of the delivery time passed
-// to the aggregator
-func (pa *PostcodeAggregator) checkDeliveryInTimespan(recipe *model.Recipe) bool {
-	return recipe.From.InclusiveBetween(pa.aggrDeliveryFrom, pa.aggrDeliveryTo) &&
-		recipe.To.InclusiveBetween(pa.aggrDeliveryFrom, pa.aggrDeliveryTo)
+// checkPostcodeEquals checks whether the postcode of the query is equal the postcode of a recipe
+func (q PostcodeQuery) checkPostcodeEquals(recipe *model.Recipe) bool {
+	return q.Postcode == recipe.Postcode
 }
 
-func (pa *PostcodeAggregator) incrementPostcodeCount() {
-	pa.postcodeTimeCount.DeliveryCount++
+// match checks if recipe delivery timespan matches the timespan of the query on one of its weekdays according to mode
+// and returns the minutes the delivery has in common with the matched timespans. deliveries crossing midnight are
+// matched against the weekday they start on
+func (q PostcodeQuery) match(recipe *model.Recipe, mode model.MatchMode) (bool, int) {
+	delivery := recipe.DeliveryWindow()
+
+	var (
+		matched        bool
+		overlapMinutes int
+	)
+	for _, day := range q.days() {
+		window := model.DeliveryWindow{Weekday: day, From: q.DeliveryFrom, To: q.DeliveryTo}
+		if delivery.Matches(window, mode) {
+			matched = true
+			overlapMinutes += delivery.Overlap(window)
+		}
+	}
+	return matched, overlapMinutes
+}
+
+// days returns the weekdays the query timespan is counted on, every weekday when the query has no days
+func (q PostcodeQuery) days() []time.Weekday {
+	if len(q.Days) == 0 {
+		return model.Weekdays
+	}
+	return q.Days
 }
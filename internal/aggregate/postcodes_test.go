@@ -4,28 +4,24 @@ import (
 	"github.com/davido912-recipe-count-test-2020/internal/model"
 	"github.com/davido912-recipe-count-test-2020/internal/testutils"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
+	"time"
 )
 
 func mockPostcodeAggr(aggrInput AggregatorInput) *PostcodeAggregator {
-	return &PostcodeAggregator{
-		postcodeMap: make(postcodeMap, DistinctPostcodesCap),
-		postcodeTimeCount: model.PostcodeTimeCount{
-			Postcode:      aggrInput.Postcode,
-			From:          aggrInput.DeliveryFrom.Raw(),
-			To:            aggrInput.DeliveryTo.Raw(),
-			DeliveryCount: 0,
-		},
-		aggrDeliveryTo:   aggrInput.DeliveryTo,
-		aggrDeliveryFrom: aggrInput.DeliveryFrom,
-	}
+	return newPostcodeAggregator(&aggrInput)
 }
 
 func TestPostcodeAggregator_Aggregate(t *testing.T) {
 	aggrInput := AggregatorInput{
-		Postcode:     "10245",
-		DeliveryFrom: testutils.MockDeliveryTime("12PM"),
-		DeliveryTo:   testutils.MockDeliveryTime("5PM"),
+		Queries: []PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("12PM"),
+				DeliveryTo:   testutils.MockDeliveryTime("5PM"),
+			},
+		},
 	}
 	aggr := mockPostcodeAggr(aggrInput)
 	recipes := testutils.MockRecipes()
@@ -39,14 +35,18 @@ func TestPostcodeAggregator_Aggregate(t *testing.T) {
 		"10311": 2,
 	}
 	assert.Equal(t, wantMap, aggr.postcodeMap)
-	assert.Equal(t, 2, aggr.postcodeTimeCount.DeliveryCount)
+	assert.Equal(t, 2, aggr.postcodeTimeCounts[0].DeliveryCount)
 }
 
 func TestPostcodeAggregator_GetBusiestPostcode(t *testing.T) {
 	aggrInput := AggregatorInput{
-		Postcode:     "10245",
-		DeliveryFrom: testutils.MockDeliveryTime("12PM"),
-		DeliveryTo:   testutils.MockDeliveryTime("5PM"),
+		Queries: []PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("12PM"),
+				DeliveryTo:   testutils.MockDeliveryTime("5PM"),
+			},
+		},
 	}
 	aggr := mockPostcodeAggr(aggrInput)
 	recipes := testutils.MockRecipes()
@@ -61,11 +61,93 @@ func TestPostcodeAggregator_GetBusiestPostcode(t *testing.T) {
 	assert.Equal(t, want, aggr.GetBusiestPostcode())
 }
 
-func TestPostcodeAggregator_GetPostcodeTimeCount(t *testing.T) {
+func TestPostcodeAggregator_GetBusiestPostcodeTie(t *testing.T) {
+	aggr := &PostcodeAggregator{postcodeMap: postcodeMap{"10342": 2, "10245": 2, "10311": 2, "10120": 1}}
+
+	// ties must be broken the same way regardless of the map iteration order
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, model.PostcodeCount{Postcode: "10245", DeliveryCount: 2}, aggr.GetBusiestPostcode())
+	}
+}
+
+func TestPostcodeAggregator_GetTopPostcodes(t *testing.T) {
+	tcs := []struct {
+		name         string
+		topPostcodes int
+		postcodeMap  postcodeMap
+		want         []model.RankedPostcodeCount
+	}{
+		{
+			name:         "ranking disabled",
+			topPostcodes: 0,
+			postcodeMap:  postcodeMap{"10245": 3},
+			want:         nil,
+		},
+		{
+			name:         "ties broken by postcode ascending",
+			topPostcodes: 3,
+			postcodeMap:  postcodeMap{"10342": 2, "10311": 2, "10245": 3, "10120": 1},
+			want: []model.RankedPostcodeCount{
+				{Rank: 1, Postcode: "10245", DeliveryCount: 3, Share: 37.5},
+				{Rank: 2, Postcode: "10311", DeliveryCount: 2, Share: 25},
+				{Rank: 3, Postcode: "10342", DeliveryCount: 2, Share: 25},
+			},
+		},
+		{
+			name:         "fewer postcodes than requested",
+			topPostcodes: 5,
+			postcodeMap:  postcodeMap{"10245": 2, "10120": 1},
+			want: []model.RankedPostcodeCount{
+				{Rank: 1, Postcode: "10245", DeliveryCount: 2, Share: 66.67},
+				{Rank: 2, Postcode: "10120", DeliveryCount: 1, Share: 33.33},
+			},
+		},
+		{
+			name:         "no deliveries",
+			topPostcodes: 5,
+			postcodeMap:  postcodeMap{},
+			want:         []model.RankedPostcodeCount{},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			aggr := &PostcodeAggregator{postcodeMap: tc.postcodeMap, topPostcodes: tc.topPostcodes}
+			assert.Equal(t, tc.want, aggr.GetTopPostcodes())
+		})
+	}
+}
+
+func TestPostcodeAggregator_GetPostcodeCounts(t *testing.T) {
+	aggr := &PostcodeAggregator{postcodeMap: postcodeMap{"10342": 2, "10120": 1, "10245": 3}}
+
+	want := []model.PostcodeCount{
+		{Postcode: "10120", DeliveryCount: 1},
+		{Postcode: "10245", DeliveryCount: 3},
+		{Postcode: "10342", DeliveryCount: 2},
+	}
+	assert.Equal(t, want, aggr.GetPostcodeCounts())
+}
+
+func TestPostcodeAggregator_GetPostcodeTimeCounts(t *testing.T) {
 	aggrInput := AggregatorInput{
-		Postcode:     "10245",
-		DeliveryFrom: testutils.MockDeliveryTime("12PM"),
-		DeliveryTo:   testutils.MockDeliveryTime("5PM"),
+		Queries: []PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("12PM"),
+				DeliveryTo:   testutils.MockDeliveryTime("5PM"),
+			},
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("5PM"),
+			},
+			{
+				Postcode:     "10311",
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("5PM"),
+			},
+		},
 	}
 	aggr := mockPostcodeAggr(aggrInput)
 	recipes := testutils.MockRecipes()
@@ -73,13 +155,170 @@ func TestPostcodeAggregator_GetPostcodeTimeCount(t *testing.T) {
 		aggr.aggregate(recipe)
 	}
 
-	want := model.PostcodeTimeCount{
-		Postcode:      "10245",
-		From:          aggrInput.DeliveryFrom.Raw(),
-		To:            aggrInput.DeliveryTo.Raw(),
-		DeliveryCount: 2,
+	want := []model.PostcodeTimeCount{
+		{Postcode: "10245", From: "12PM", To: "5PM", DeliveryCount: 2},
+		{Postcode: "10245", From: "10AM", To: "5PM", DeliveryCount: 3},
+		{Postcode: "10311", From: "10AM", To: "5PM", DeliveryCount: 2},
+	}
+	assert.Equal(t, want, aggr.GetPostcodeTimeCounts())
+}
+
+func TestPostcodeAggregator_GetPostcodeTimeCountsTimeFormat(t *testing.T) {
+	aggr := newPostcodeAggregator(&AggregatorInput{
+		Queries: []PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("9:30AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("17:00"),
+			},
+		},
+		TimeFormat: model.TimeFormat12h,
+	})
+	aggr.aggregate(&model.Recipe{
+		Postcode: "10245",
+		From:     testutils.MockDeliveryTime("10:15"),
+		To:       testutils.MockDeliveryTime("4:45PM"),
+	})
+
+	want := []model.PostcodeTimeCount{{Postcode: "10245", From: "9:30AM", To: "5PM", DeliveryCount: 1}}
+	assert.Equal(t, want, aggr.GetPostcodeTimeCounts())
+}
+
+func TestPostcodeAggregator_GetPostcodeTimeCountsMatchModes(t *testing.T) {
+	aggr := newPostcodeAggregator(&AggregatorInput{
+		Queries: []PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+			},
+		},
+		MatchModes: []model.MatchMode{
+			model.MatchOverlaps, model.MatchContained, model.MatchStartsWithin, model.MatchEndsWithin,
+		},
+	})
+	for _, delivery := range []string{"11AM-1PM", "9AM-11AM", "2PM-5PM", "8AM-6PM", "4PM-5PM"} {
+		times := strings.Split(delivery, "-")
+		aggr.aggregate(&model.Recipe{
+			Postcode: "10245",
+			From:     testutils.MockDeliveryTime(times[0]),
+			To:       testutils.MockDeliveryTime(times[1]),
+		})
+	}
+
+	want := []model.PostcodeTimeCount{
+		{
+			Postcode:      "10245",
+			From:          "10AM",
+			To:            "3PM",
+			DeliveryCount: 4,
+			Matches: []model.WindowMatch{
+				{Mode: model.MatchOverlaps, DeliveryCount: 4, OverlapMinutes: 120 + 60 + 60 + 300},
+				{Mode: model.MatchContained, DeliveryCount: 1, OverlapMinutes: 120},
+				{Mode: model.MatchStartsWithin, DeliveryCount: 2, OverlapMinutes: 120 + 60},
+				{Mode: model.MatchEndsWithin, DeliveryCount: 2, OverlapMinutes: 120 + 60},
+			},
+		},
+	}
+	assert.Equal(t, want, aggr.GetPostcodeTimeCounts())
+}
+
+func TestPostcodeQuery_matchDays(t *testing.T) {
+	tcs := []struct {
+		name     string
+		query    PostcodeQuery
+		delivery string
+		weekday  time.Weekday
+		want     bool
+	}{
+		{
+			name: "no days match every weekday",
+			query: PostcodeQuery{
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+			},
+			delivery: "11AM-1PM",
+			weekday:  time.Sunday,
+			want:     true,
+		},
+		{
+			name: "weekday in days",
+			query: PostcodeQuery{
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+				Days:         []time.Weekday{time.Monday, time.Tuesday},
+			},
+			delivery: "11AM-1PM",
+			weekday:  time.Tuesday,
+			want:     true,
+		},
+		{
+			name: "weekday not in days",
+			query: PostcodeQuery{
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+				Days:         []time.Weekday{time.Monday, time.Tuesday},
+			},
+			delivery: "11AM-1PM",
+			weekday:  time.Wednesday,
+			want:     false,
+		},
+		{
+			name: "overnight delivery in overnight timespan",
+			query: PostcodeQuery{
+				DeliveryFrom: testutils.MockDeliveryTime("8PM"),
+				DeliveryTo:   testutils.MockDeliveryTime("2AM"),
+			},
+			delivery: "10PM-2AM",
+			weekday:  time.Friday,
+			want:     true,
+		},
+		{
+			name: "overnight delivery not in same day timespan",
+			query: PostcodeQuery{
+				DeliveryFrom: testutils.MockDeliveryTime("1PM"),
+				DeliveryTo:   testutils.MockDeliveryTime("11PM"),
+			},
+			delivery: "10PM-2AM",
+			weekday:  time.Friday,
+			want:     false,
+		},
+		{
+			name: "delivery after midnight counted for the day the timespan starts on",
+			query: PostcodeQuery{
+				DeliveryFrom: testutils.MockDeliveryTime("8PM"),
+				DeliveryTo:   testutils.MockDeliveryTime("2AM"),
+				Days:         []time.Weekday{time.Friday},
+			},
+			delivery: "12AM-1AM",
+			weekday:  time.Saturday,
+			want:     true,
+		},
+		{
+			name: "delivery after midnight not counted for the next day",
+			query: PostcodeQuery{
+				DeliveryFrom: testutils.MockDeliveryTime("8PM"),
+				DeliveryTo:   testutils.MockDeliveryTime("2AM"),
+				Days:         []time.Weekday{time.Saturday},
+			},
+			delivery: "12AM-1AM",
+			weekday:  time.Saturday,
+			want:     false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			times := strings.Split(tc.delivery, "-")
+			recipe := &model.Recipe{
+				From:    testutils.MockDeliveryTime(times[0]),
+				To:      testutils.MockDeliveryTime(times[1]),
+				Weekday: tc.weekday,
+			}
+			got, _ := tc.query.match(recipe, model.MatchContained)
+			assert.Equal(t, tc.want, got)
+		})
 	}
-	assert.Equal(t, want, aggr.GetPostcodeTimeCount())
 }
 
 func TestPostcodeMap_Add(t *testing.T) {
@@ -125,31 +364,35 @@ func TestPostcodeMap_Add(t *testing.T) {
 	}
 }
 
-func TestCheckDeliveryInTimespan(t *testing.T) {
+func TestPostcodeQuery_match(t *testing.T) {
 	aggrInput := AggregatorInput{
-		Postcode:     "10245",
-		DeliveryFrom: testutils.MockDeliveryTime("10AM"),
-		DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+		Queries: []PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+			},
+		},
 	}
-	aggr := mockPostcodeAggr(aggrInput)
+	query := aggrInput.Queries[0]
 
 	tcs := []struct {
 		name       string
-		aggr       *PostcodeAggregator
+		query      PostcodeQuery
 		recipeFrom *model.DeliveryTime
 		recipeTo   *model.DeliveryTime
 		want       bool
 	}{
 		{
 			name:       "delivery in timespan",
-			aggr:       aggr,
+			query:      query,
 			recipeFrom: testutils.MockDeliveryTime("11AM"),
 			recipeTo:   testutils.MockDeliveryTime("1PM"),
 			want:       true,
 		},
 		{
 			name:       "delivery not in timespan",
-			aggr:       aggr,
+			query:      query,
 			recipeFrom: testutils.MockDeliveryTime("9AM"),
 			recipeTo:   testutils.MockDeliveryTime("1PM"),
 			want:       false,
@@ -162,7 +405,7 @@ func TestCheckDeliveryInTimespan(t *testing.T) {
 				From: tc.recipeFrom,
 				To:   tc.recipeTo,
 			}
-			got := aggr.checkDeliveryInTimespan(recipe)
+			got, _ := tc.query.match(recipe, model.MatchContained)
 			assert.Equal(t, tc.want, got)
 
 		})
@@ -173,27 +416,31 @@ func TestCheckPostcodeEquals(t *testing.T) {
 	postcode := "15231"
 
 	aggrInput := AggregatorInput{
-		Postcode:     postcode,
-		DeliveryFrom: testutils.MockDeliveryTime("10AM"),
-		DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+		Queries: []PostcodeQuery{
+			{
+				Postcode:     postcode,
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+			},
+		},
 	}
-	aggr := mockPostcodeAggr(aggrInput)
+	query := aggrInput.Queries[0]
 
 	tcs := []struct {
 		name          string
-		aggr          *PostcodeAggregator
+		query         PostcodeQuery
 		checkPostcode string
 		want          bool
 	}{
 		{
 			name:          "postcode matches",
-			aggr:          aggr,
+			query:         query,
 			checkPostcode: postcode,
 			want:          true,
 		},
 		{
 			name:          "postcode does not match",
-			aggr:          aggr,
+			query:         query,
 			checkPostcode: "22",
 			want:          false,
 		},
@@ -202,7 +449,7 @@ func TestCheckPostcodeEquals(t *testing.T) {
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			recipe := &model.Recipe{Postcode: tc.checkPostcode}
-			got := tc.aggr.checkPostcodeEquals(recipe)
+			got := tc.query.checkPostcodeEquals(recipe)
 			assert.Equal(t, tc.want, got)
 
 		})
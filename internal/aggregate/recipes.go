@@ -1,10 +1,10 @@
 package aggregate
 
 import (
+	"errors"
 	"fmt"
 	"github.com/davido912-recipe-count-test-2020/internal/model"
 	"sort"
-	"strings"
 )
 
 const (
@@ -12,15 +12,19 @@ const (
 	DistinctRecipeCap       = 2000
 )
 
+var ErrRecipeNameTooLong = errors.New("recipe name too long")
+
 type (
 
 	// recipeMap represents recipe names and their counts
 	recipeMap map[string]int
 
-	// recipeMatcher case-sensitive term matching recipe names to terms passed by the user
+	// recipeMatcher matches recipe names to terms passed by the user. matches holds every matching recipe name once,
+	// termMatches the recipe names matched by every term at the same index
 	recipeMatcher struct {
-		matches []string
-		terms   []string
+		matches     []string
+		termMatches [][]model.MatchedRecipe
+		terms       *TermMatcher
 	}
 
 	RecipeAggregator struct {
@@ -30,6 +34,24 @@ type (
 	}
 )
 
+// newRecipeAggregator returns a RecipeAggregator matching the recipe names with the TermMatcher of aggrInput, or with
+// its Terms as case-sensitive substrings when it has no TermMatcher
+func newRecipeAggregator(aggrInput *AggregatorInput) *RecipeAggregator {
+	terms := aggrInput.TermMatcher
+	if terms == nil {
+		// substring terms are quoted, compiling them cannot fail
+		terms, _ = NewTermMatcher(aggrInput.Terms, TermMatchSubstring, false)
+	}
+
+	return &RecipeAggregator{
+		recipeMap: make(recipeMap, DistinctRecipeCap),
+		recipeMatcher: recipeMatcher{
+			matches: make([]string, 0, DistinctRecipeCap),
+			terms:   terms,
+		},
+	}
+}
+
 // aggregate aggregates primary data required for this component.
 func (ra *RecipeAggregator) aggregate(recipe *model.Recipe) {
 	_ = ra.add(recipe)
@@ -44,6 +66,10 @@ func (ra *RecipeAggregator) postAggregate() {
 	}
 
 	sort.Strings(ra.sortedRecipeNames)
+
+	for _, recipe := range ra.sortedRecipeNames {
+		ra.match(recipe)
+	}
 }
 
 func (ra *RecipeAggregator) GetUniqueRecipeCount() int {
@@ -66,28 +92,93 @@ func (ra *RecipeAggregator) GetRecipeCountsModel() model.RecipeCounts {
 	return recipeCounts
 }
 
+// GetRecipeMatches returns the names of the recipes matching any of the terms, sorted in ascending order
 func (ra *RecipeAggregator) GetRecipeMatches() model.RecipeMatches {
-	for _, recipe := range ra.sortedRecipeNames {
-		ra.match(recipe)
-	}
 	return ra.recipeMatcher.matches
 }
 
+// GetTermMatches returns the recipes matched by every term, in the order the terms were passed, along with the terms
+// that matched no recipe at all. the recipes of a term are sorted by name in ascending order
+func (ra *RecipeAggregator) GetTermMatches() ([]model.TermMatch, []string) {
+	terms := ra.terms.Terms()
+	termMatches := make([]model.TermMatch, 0, len(terms))
+	unmatchedTerms := make([]string, 0)
+
+	for i, term := range terms {
+		termMatch := model.TermMatch{Term: term, Recipes: []model.MatchedRecipe{}}
+		if i < len(ra.termMatches) {
+			for _, matched := range ra.termMatches[i] {
+				matched.RecipeCount = ra.recipeMap[matched.Recipe]
+				termMatch.Recipes = append(termMatch.Recipes, matched)
+				termMatch.DeliveryCount += matched.RecipeCount
+			}
+		}
+		if len(termMatch.Recipes) == 0 {
+			unmatchedTerms = append(unmatchedTerms, term)
+		}
+		termMatches = append(termMatches, termMatch)
+	}
+	return termMatches, unmatchedTerms
+}
+
 func (r *recipeMatcher) append(recipeName string) {
 	r.matches = append(r.matches, recipeName)
 }
 
+// match records recipeName for every term it matches, with its similarity to the term in fuzzy mode. a recipe
+// matching several terms is listed once in the matches
 func (r *recipeMatcher) match(recipeName string) {
-	for _, word := range r.terms {
-		if strings.Contains(recipeName, word) {
-			r.append(recipeName)
+	terms := r.terms.Terms()
+	if r.termMatches == nil {
+		r.termMatches = make([][]model.MatchedRecipe, len(terms))
+	}
+
+	var matched bool
+	for i := range terms {
+		ok, score := r.terms.match(i, recipeName)
+		if !ok {
+			continue
 		}
+
+		matchedRecipe := model.MatchedRecipe{Recipe: recipeName}
+		if r.terms.fuzzy() {
+			matchedRecipe.Score = score
+		}
+		r.termMatches[i] = append(r.termMatches[i], matchedRecipe)
+		matched = true
+	}
+	if matched {
+		r.append(recipeName)
 	}
 }
 
-func (rm recipeMap) add(recipe *model.Recipe) error {
+// validateRecipeName ensures the recipe name is within the length constraint
+func validateRecipeName(recipe *model.Recipe) error {
 	if len(recipe.Recipe) > RecipeNameLenConstraint {
-		return fmt.Errorf("recipe name: %s is longer than limit: %d", recipe.Recipe, RecipeNameLenConstraint)
+		return fmt.Errorf("%w: recipe name: %s is longer than limit: %d",
+			ErrRecipeNameTooLong, recipe.Recipe, RecipeNameLenConstraint)
+	}
+	return nil
+}
+
+// recipeCounts returns the counts of the recipes sorted by recipe name in ascending order
+func (rm recipeMap) recipeCounts() model.RecipeCounts {
+	names := make([]string, 0, len(rm))
+	for name := range rm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	recipeCounts := make(model.RecipeCounts, 0, len(names))
+	for _, name := range names {
+		recipeCounts = append(recipeCounts, model.RecipeCount{Recipe: name, RecipeCount: rm[name]})
+	}
+	return recipeCounts
+}
+
+func (rm recipeMap) add(recipe *model.Recipe) error {
+	if err := validateRecipeName(recipe); err != nil {
+		return err
 	}
 	rm[recipe.Recipe]++
 	return nil
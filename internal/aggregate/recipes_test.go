@@ -7,13 +7,21 @@ import (
 	"testing"
 )
 
+func mockTermMatcher(mode TermMatchMode, ignoreCase bool, terms ...string) *TermMatcher {
+	termMatcher, err := NewTermMatcher(terms, mode, ignoreCase)
+	if err != nil {
+		panic(err)
+	}
+	return termMatcher
+}
+
 func TestRecipeAggregator_Aggregate(t *testing.T) {
 	recipes := testutils.MockRecipes()
 
 	aggr := RecipeAggregator{
 		recipeMap: make(recipeMap),
 		recipeMatcher: recipeMatcher{
-			terms: []string{"ea"},
+			terms: mockTermMatcher(TermMatchSubstring, false, "ea"),
 		},
 	}
 	for _, recipe := range recipes {
@@ -40,7 +48,7 @@ func TestRecipeAggregator_GetUniqueRecipeCount(t *testing.T) {
 	aggr := RecipeAggregator{
 		recipeMap: make(recipeMap),
 		recipeMatcher: recipeMatcher{
-			terms: []string{},
+			terms: mockTermMatcher(TermMatchSubstring, false),
 		},
 	}
 	for _, recipe := range recipes {
@@ -58,7 +66,7 @@ func TestRecipeAggregator_GetRecipeCountsModel(t *testing.T) {
 	aggr := RecipeAggregator{
 		recipeMap: make(recipeMap),
 		recipeMatcher: recipeMatcher{
-			terms: []string{},
+			terms: mockTermMatcher(TermMatchSubstring, false),
 		},
 	}
 	for _, recipe := range recipes {
@@ -81,7 +89,7 @@ func TestRecipeAggregator_GetRecipeCountsModel(t *testing.T) {
 func TestRecipeMatcher_match(t *testing.T) {
 	rm := recipeMatcher{
 		matches: []string{},
-		terms:   []string{"Apple"},
+		terms:   mockTermMatcher(TermMatchSubstring, false, "Apple"),
 	}
 
 	rm.match("Honey Apple Pie")
@@ -89,3 +97,61 @@ func TestRecipeMatcher_match(t *testing.T) {
 	want := []string{"Honey Apple Pie"}
 	assert.Equal(t, want, rm.matches)
 }
+
+func TestRecipeAggregator_GetTermMatches(t *testing.T) {
+	aggr := newRecipeAggregator(&AggregatorInput{
+		TermMatcher: mockTermMatcher(TermMatchSubstring, false, "ea", "Ste", "Potato"),
+	})
+	for _, recipe := range testutils.MockRecipes() {
+		aggr.aggregate(recipe)
+	}
+	aggr.postAggregate()
+
+	// Steak matches two terms but is listed once
+	assert.Equal(t, model.RecipeMatches{"Pear", "Steak"}, aggr.GetRecipeMatches())
+
+	termMatches, unmatchedTerms := aggr.GetTermMatches()
+	wantTermMatches := []model.TermMatch{
+		{
+			Term:          "ea",
+			DeliveryCount: 2,
+			Recipes:       []model.MatchedRecipe{{Recipe: "Pear", RecipeCount: 1}, {Recipe: "Steak", RecipeCount: 1}},
+		},
+		{
+			Term:          "Ste",
+			DeliveryCount: 1,
+			Recipes:       []model.MatchedRecipe{{Recipe: "Steak", RecipeCount: 1}},
+		},
+		{
+			Term:    "Potato",
+			Recipes: []model.MatchedRecipe{},
+		},
+	}
+	assert.Equal(t, wantTermMatches, termMatches)
+	assert.Equal(t, []string{"Potato"}, unmatchedTerms)
+}
+
+func TestRecipeAggregator_GetTermMatchesFuzzy(t *testing.T) {
+	aggr := newRecipeAggregator(&AggregatorInput{
+		TermMatcher: mockTermMatcher(TermMatchFuzzy, true, "mushroom risotto"),
+	})
+	for _, name := range []string{"Mushroom Risotto", "Mushroom Risoto", "Mushrom Risotto", "Mushroom Risoto", "Tacos"} {
+		aggr.aggregate(&model.Recipe{Recipe: name})
+	}
+	aggr.postAggregate()
+
+	termMatches, unmatchedTerms := aggr.GetTermMatches()
+	want := []model.TermMatch{
+		{
+			Term:          "mushroom risotto",
+			DeliveryCount: 4,
+			Recipes: []model.MatchedRecipe{
+				{Recipe: "Mushrom Risotto", RecipeCount: 1, Score: 0.94},
+				{Recipe: "Mushroom Risoto", RecipeCount: 2, Score: 0.94},
+				{Recipe: "Mushroom Risotto", RecipeCount: 1, Score: 1},
+			},
+		},
+	}
+	assert.Equal(t, want, termMatches)
+	assert.Equal(t, []string{}, unmatchedTerms)
+}
@@ -0,0 +1,266 @@
+package aggregate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultFuzzyThreshold is the similarity a recipe name must at least have with a term to match it in fuzzy mode
+const DefaultFuzzyThreshold = 0.8
+
+// TermMatchMode decides how the match terms are matched with the recipe names
+type TermMatchMode string
+
+const (
+	// TermMatchSubstring matches the recipe names containing the term
+	TermMatchSubstring TermMatchMode = "substring"
+
+	// TermMatchWord matches the recipe names containing the term as whole words, e.g. "Veg" does not match "Veggie"
+	TermMatchWord TermMatchMode = "word"
+
+	// TermMatchRegex matches the recipe names the term, a regular expression, matches anywhere in
+	TermMatchRegex TermMatchMode = "regex"
+
+	// TermMatchGlob matches the recipe names matching the whole term, a glob pattern with *, ? and [...] classes
+	TermMatchGlob TermMatchMode = "glob"
+
+	// TermMatchFuzzy matches the recipe names containing words similar to the term, e.g. "Mushroom Risotto" matches
+	// "Mushrom Risoto". see similarity
+	TermMatchFuzzy TermMatchMode = "fuzzy"
+)
+
+// TermMatchModes lists all the supported term match modes
+var TermMatchModes = []TermMatchMode{TermMatchSubstring, TermMatchWord, TermMatchRegex, TermMatchGlob, TermMatchFuzzy}
+
+var ErrInvalidTerm = errors.New("invalid match term")
+
+// ParseTermMatchMode validates that mode is one of the supported term match modes
+func ParseTermMatchMode(mode string) (TermMatchMode, error) {
+	for _, matchMode := range TermMatchModes {
+		if TermMatchMode(strings.ToLower(mode)) == matchMode {
+			return matchMode, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported recipe match mode %q, must be one of %v", mode, TermMatchModes)
+}
+
+// TermMatcher matches recipe names with terms, every term is compiled once into a pattern when the matcher is created.
+// in fuzzy mode the terms are compared with the recipe names instead
+type TermMatcher struct {
+	terms    []string
+	patterns []*regexp.Regexp
+
+	// fuzzyTerms are the normalized terms compared in fuzzy mode, a name matches once its similarity with a term
+	// reaches the threshold
+	fuzzyTerms []string
+	ignoreCase bool
+	threshold  float64
+}
+
+// NewTermMatcher compiles the terms according to mode. with ignoreCase the terms match regardless of case, using
+// Unicode case folding (e.g. "ÄPFEL" matches "äpfel"). ErrInvalidTerm is returned for invalid regex or glob terms
+func NewTermMatcher(terms []string, mode TermMatchMode, ignoreCase bool) (*TermMatcher, error) {
+	tm := &TermMatcher{terms: terms, ignoreCase: ignoreCase, threshold: DefaultFuzzyThreshold}
+	if mode == TermMatchFuzzy {
+		tm.fuzzyTerms = make([]string, 0, len(terms))
+		for _, term := range terms {
+			tm.fuzzyTerms = append(tm.fuzzyTerms, tm.normalize(term))
+		}
+		return tm, nil
+	}
+
+	tm.patterns = make([]*regexp.Regexp, 0, len(terms))
+	for _, term := range terms {
+		expr, err := termExpr(term, mode)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrInvalidTerm, term, err)
+		}
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %s", ErrInvalidTerm, term, err)
+		}
+		tm.patterns = append(tm.patterns, pattern)
+	}
+	return tm, nil
+}
+
+// Terms returns the terms of the matcher as they were passed
+func (tm *TermMatcher) Terms() []string {
+	return tm.terms
+}
+
+// SetFuzzyThreshold sets the similarity (0 to 1) a recipe name must at least have with a term to match it in fuzzy
+// mode, DefaultFuzzyThreshold by default
+func (tm *TermMatcher) SetFuzzyThreshold(threshold float64) {
+	tm.threshold = threshold
+}
+
+// fuzzy returns whether the terms are matched in fuzzy mode
+func (tm *TermMatcher) fuzzy() bool {
+	return tm.fuzzyTerms != nil
+}
+
+// match returns whether the term at index i matches name along with the similarity of both in fuzzy mode, rounded to
+// 2 decimals. the similarity is 1 in the other modes
+func (tm *TermMatcher) match(i int, name string) (bool, float64) {
+	if !tm.fuzzy() {
+		return tm.patterns[i].MatchString(name), 1
+	}
+
+	score := math.Round(similarity(tm.fuzzyTerms[i], tm.normalize(name))*100) / 100
+	return score >= tm.threshold, score
+}
+
+// normalize collapses the whitespace of value, and folds its case when case is ignored
+func (tm *TermMatcher) normalize(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if tm.ignoreCase {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// similarity returns how similar the best matching run of words of name is to term, from 0 to 1. runs have as many
+// words as term, a similarity of 1 minus the edit distance relative to the length of the longer of both, so that term
+// is found within longer names. names with fewer words than term are compared as a whole
+func similarity(term, name string) float64 {
+	termWords := strings.Count(term, " ") + 1
+	nameWords := strings.Fields(name)
+	if len(nameWords) <= termWords {
+		return windowSimilarity(term, name)
+	}
+
+	var best float64
+	for i := 0; i+termWords <= len(nameWords); i++ {
+		window := strings.Join(nameWords[i:i+termWords], " ")
+		if sim := windowSimilarity(term, window); sim > best {
+			best = sim
+		}
+	}
+	return best
+}
+
+// windowSimilarity returns 1 minus the edit distance of a and b relative to the length of the longer of both
+func windowSimilarity(a, b string) float64 {
+	longest := utf8.RuneCountInString(a)
+	if n := utf8.RuneCountInString(b); n > longest {
+		longest = n
+	}
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// levenshtein returns the number of rune insertions, deletions and substitutions turning a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+// termExpr returns the regular expression matching the names that term matches according to mode
+func termExpr(term string, mode TermMatchMode) (string, error) {
+	switch mode {
+	case TermMatchWord:
+		// letters, digits and underscores of any script make up words
+		return `(?:^|[^\p{L}\p{N}_])` + regexp.QuoteMeta(term) + `(?:$|[^\p{L}\p{N}_])`, nil
+	case TermMatchRegex:
+		return term, nil
+	case TermMatchGlob:
+		return globExpr(term)
+	default:
+		return regexp.QuoteMeta(term), nil
+	}
+}
+
+// globExpr translates a glob pattern into a regular expression matching whole names. * matches any run of
+// characters, ? a single character, [...] a character class ([!...] negated) and \ escapes the next character
+func globExpr(glob string) (string, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '\\':
+			if i+1 == len(runes) {
+				return "", errors.New("trailing escape character")
+			}
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			end := globClassEnd(runes, i)
+			if end < 0 {
+				return "", errors.New("unterminated character class")
+			}
+			class := string(runes[i+1 : end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i = end
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	expr.WriteString("$")
+	return expr.String(), nil
+}
+
+// globClassEnd returns the index of the ] closing the character class opened at start, -1 when it is not closed. a ]
+// right after the opening [ (or [!) is part of the class
+func globClassEnd(runes []rune, start int) int {
+	i := start + 1
+	if i < len(runes) && runes[i] == '!' {
+		i++
+	}
+	if i < len(runes) && runes[i] == ']' {
+		i++
+	}
+	for ; i < len(runes); i++ {
+		if runes[i] == ']' {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,162 @@
+package aggregate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTermMatcher(t *testing.T) {
+	names := []string{"Potato Salad", "Sweet potato", "Veggie Burger", "Veg Curry", "Äpfel Strudel", "Honey_Veg Mix"}
+
+	tcs := []struct {
+		name       string
+		mode       TermMatchMode
+		ignoreCase bool
+		term       string
+		want       []string
+	}{
+		{
+			name: "substring is case-sensitive",
+			mode: TermMatchSubstring,
+			term: "Potato",
+			want: []string{"Potato Salad"},
+		},
+		{
+			name:       "substring ignoring case",
+			mode:       TermMatchSubstring,
+			ignoreCase: true,
+			term:       "potato",
+			want:       []string{"Potato Salad", "Sweet potato"},
+		},
+		{
+			name: "substring matches within words",
+			mode: TermMatchSubstring,
+			term: "Veg",
+			want: []string{"Veggie Burger", "Veg Curry", "Honey_Veg Mix"},
+		},
+		{
+			name: "whole words only",
+			mode: TermMatchWord,
+			term: "Veg",
+			want: []string{"Veg Curry"},
+		},
+		{
+			name:       "unicode case folding",
+			mode:       TermMatchWord,
+			ignoreCase: true,
+			term:       "äPFEL",
+			want:       []string{"Äpfel Strudel"},
+		},
+		{
+			name: "regex",
+			mode: TermMatchRegex,
+			term: `^(Veg|Sweet)\b`,
+			want: []string{"Sweet potato", "Veg Curry"},
+		},
+		{
+			name: "glob matches whole names",
+			mode: TermMatchGlob,
+			term: "*potato",
+			want: []string{"Sweet potato"},
+		},
+		{
+			name:       "fuzzy matches typos",
+			mode:       TermMatchFuzzy,
+			ignoreCase: true,
+			term:       "sweet potatoe",
+			want:       []string{"Sweet potato"},
+		},
+		{
+			name: "fuzzy matches words within longer names",
+			mode: TermMatchFuzzy,
+			term: "Vegie",
+			want: []string{"Veggie Burger"},
+		},
+		{
+			name:       "glob with classes ignoring case",
+			mode:       TermMatchGlob,
+			ignoreCase: true,
+			term:       "[!s]*_veg ???",
+			want:       []string{"Honey_Veg Mix"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			termMatcher := mockTermMatcher(tc.mode, tc.ignoreCase, tc.term)
+
+			var got []string
+			for _, name := range names {
+				if matched, _ := termMatcher.match(0, name); matched {
+					got = append(got, name)
+				}
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tcs := []struct {
+		term string
+		name string
+		want float64
+	}{
+		{term: "Mushroom Risotto", name: "Mushroom Risotto", want: 1},
+		{term: "Mushroom Risotto", name: "Mushroom Risoto", want: 1 - 1.0/16},
+		{term: "Mushroom Risotto", name: "Creamy Mushrom Risotto Bowl", want: 1 - 1.0/16},
+		{term: "Risotto", name: "Mushroom Risoto", want: 1 - 1.0/7},
+		{term: "Mushroom Risotto", name: "Risotto", want: 1 - 9.0/16},
+		{term: "Kürbis", name: "Kurbis", want: 1 - 1.0/6},
+		{term: "", name: "", want: 1},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.term+"/"+tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.want, similarity(tc.term, tc.name), 1e-9)
+		})
+	}
+}
+
+func TestTermMatcher_SetFuzzyThreshold(t *testing.T) {
+	termMatcher := mockTermMatcher(TermMatchFuzzy, false, "Mushroom Risotto")
+
+	matched, score := termMatcher.match(0, "Mushrom  Risoto")
+	assert.True(t, matched)
+	assert.Equal(t, 0.88, score)
+
+	termMatcher.SetFuzzyThreshold(0.9)
+	matched, _ = termMatcher.match(0, "Mushrom Risoto")
+	assert.False(t, matched)
+}
+
+func TestNewTermMatcherInvalid(t *testing.T) {
+	tcs := []struct {
+		name string
+		mode TermMatchMode
+		term string
+	}{
+		{name: "invalid regex", mode: TermMatchRegex, term: "(Potato"},
+		{name: "unterminated glob class", mode: TermMatchGlob, term: "[Pp]otato [a"},
+		{name: "trailing glob escape", mode: TermMatchGlob, term: `Potato\`},
+		{name: "invalid glob range", mode: TermMatchGlob, term: "[z-a]*"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTermMatcher([]string{"Veggie", tc.term}, tc.mode, false)
+			assert.ErrorIs(t, err, ErrInvalidTerm)
+			assert.Contains(t, err.Error(), tc.term)
+		})
+	}
+}
+
+func TestParseTermMatchMode(t *testing.T) {
+	mode, err := ParseTermMatchMode("Glob")
+	assert.Nil(t, err)
+	assert.Equal(t, TermMatchGlob, mode)
+
+	_, err = ParseTermMatchMode("exact")
+	assert.NotNil(t, err)
+}
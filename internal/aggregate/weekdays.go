@@ -0,0 +1,46 @@
+package aggregate
+
+import (
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"time"
+)
+
+// WeekdayAggregator counts the deliveries and the recipes delivered per weekday
+type WeekdayAggregator struct {
+	deliveries [7]int
+	recipes    [7]recipeMap
+}
+
+func newWeekdayAggregator() *WeekdayAggregator {
+	wa := &WeekdayAggregator{}
+	for i := range wa.recipes {
+		wa.recipes[i] = make(recipeMap)
+	}
+	return wa
+}
+
+// aggregate counts the delivery of recipe on its weekday
+func (wa *WeekdayAggregator) aggregate(recipe *model.Recipe) {
+	wa.deliveries[recipe.Weekday]++
+	_ = wa.recipes[recipe.Weekday].add(recipe)
+}
+
+// GetWeekdayCounts returns the deliveries and recipe counts of every weekday starting on Monday, recipes are sorted by
+// name in ascending order
+func (wa *WeekdayAggregator) GetWeekdayCounts() []model.WeekdayCount {
+	weekdayCounts := make([]model.WeekdayCount, 0, len(model.Weekdays))
+	for _, weekday := range model.Weekdays {
+		weekdayCounts = append(weekdayCounts, model.WeekdayCount{
+			Weekday:           weekday.String(),
+			DeliveryCount:     wa.deliveries[weekday],
+			UniqueRecipeCount: len(wa.recipes[weekday]),
+			CountPerRecipe:    wa.recipeCounts(weekday),
+		})
+	}
+	return weekdayCounts
+}
+
+// recipeCounts returns the counts of the recipes delivered on weekday sorted by recipe name in ascending order
+func (wa *WeekdayAggregator) recipeCounts(weekday time.Weekday) model.RecipeCounts {
+	return wa.recipes[weekday].recipeCounts()
+}
@@ -0,0 +1,46 @@
+package aggregate
+
+import (
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWeekdayAggregator_GetWeekdayCounts(t *testing.T) {
+	recipes := model.Recipes{
+		{Recipe: "Steak", Weekday: time.Thursday},
+		{Recipe: "Honey", Weekday: time.Thursday},
+		{Recipe: "Steak", Weekday: time.Thursday},
+		{Recipe: "Apple", Weekday: time.Sunday},
+	}
+
+	want := []model.WeekdayCount{
+		{Weekday: "Monday", CountPerRecipe: model.RecipeCounts{}},
+		{Weekday: "Tuesday", CountPerRecipe: model.RecipeCounts{}},
+		{Weekday: "Wednesday", CountPerRecipe: model.RecipeCounts{}},
+		{
+			Weekday:           "Thursday",
+			DeliveryCount:     3,
+			UniqueRecipeCount: 2,
+			CountPerRecipe: model.RecipeCounts{
+				{Recipe: "Honey", RecipeCount: 1},
+				{Recipe: "Steak", RecipeCount: 2},
+			},
+		},
+		{Weekday: "Friday", CountPerRecipe: model.RecipeCounts{}},
+		{Weekday: "Saturday", CountPerRecipe: model.RecipeCounts{}},
+		{
+			Weekday:           "Sunday",
+			DeliveryCount:     1,
+			UniqueRecipeCount: 1,
+			CountPerRecipe:    model.RecipeCounts{{Recipe: "Apple", RecipeCount: 1}},
+		},
+	}
+
+	aggr := newWeekdayAggregator()
+	for _, recipe := range recipes {
+		aggr.aggregate(recipe)
+	}
+	assert.Equal(t, want, aggr.GetWeekdayCounts())
+}
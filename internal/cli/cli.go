@@ -4,9 +4,13 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/davido912-recipe-count-test-2020/internal/aggregate"
+	"github.com/davido912-recipe-count-test-2020/internal/input"
 	"github.com/davido912-recipe-count-test-2020/internal/log"
 	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/davido912-recipe-count-test-2020/internal/processor"
 	"github.com/spf13/cobra"
 )
 
@@ -17,34 +21,91 @@ const (
 
 type CobraRunFunc func(cmd *cobra.Command, args []string) error
 
+// stdinIsTerminal reports whether stdin is attached to a terminal rather than a pipe or a file
+var stdinIsTerminal = func() bool {
+	info, err := os.Stdin.Stat()
+	return err != nil || info.Mode()&os.ModeCharDevice != 0
+}
+
 // Flags
 var (
 	LogEnabled       bool
-	Filepath         string
+	Filepaths        []string
+	InputFormat      processor.InputFormat
+	ArchiveMember    string
+	PerFileBreakdown bool
+	DLQPath          string
+	ErrorBudget      processor.ErrorBudget
+	Timeout          time.Duration
+	PartialReport    bool
 	Output           *os.File
+	Format           model.Format
+	Renderer         model.Renderer
 	MatchRecipeTerms []string
+	RecipeMatcher    *aggregate.TermMatcher
 	Postcode         string
-	DeliveryFrom     *model.DeliveryTime
+	TopPostcodes     int
+	PostcodeCounts   bool
+	Queries          []aggregate.PostcodeQuery
+	PerWeekday       bool
+	HourlyLoad       bool
+	HourlyByWeekday  bool
+	HourlyTop        int
+	TimeFormat       model.TimeFormat
+	MatchModes       []model.MatchMode
+	SingleQuery      bool
+	Metrics          []string
 	_deliveryFrom    string
-	DeliveryTo       *model.DeliveryTime
 	_deliveryTo      string
+	_queries         []string
+	_queryFile       string
+	_days            []string
 )
 
 // Flag names
 const (
 	logEnableFlag    = "log"
 	filepathFlag     = "file"
+	inputFormatFlag  = "input-format"
+	memberFlag       = "member"
+	perFileFlag      = "per-file"
+	dlqFlag          = "dlq"
+	strictFlag       = "strict"
+	maxErrorsFlag    = "max-errors"
+	maxErrorRateFlag = "max-error-rate"
+	timeoutFlag      = "timeout"
+	partialFlag      = "partial-report"
 	outputFlag       = "output"
+	formatFlag       = "format"
+	templateFlag     = "template"
 	postcodeFlag     = "count-postcode"
+	topPostcodesFlag = "top-postcodes"
+	postcodeCntsFlag = "postcode-counts"
 	deliveryToFlag   = "to"
 	deliveryFromFlag = "from"
+	queryFlag        = "query"
+	queryFileFlag    = "query-file"
+	singleQueryFlag  = "single-query-compat"
+	daysFlag         = "days"
+	perWeekdayFlag   = "per-weekday"
+	hourlyFlag       = "hourly-load"
+	hourlyDaysFlag   = "hourly-load-by-weekday"
+	hourlyTopFlag    = "hourly-load-top-postcodes"
+	timeFormatFlag   = "time-format"
+	matchModeFlag    = "match-mode"
+	metricsFlag      = "metrics"
+	matchRecipesFlag = "match-recipes"
+	recipeMatchFlag  = "recipe-match-mode"
+	ignoreCaseFlag   = "ignore-case"
+	fuzzyFlag        = "fuzzy-threshold"
 )
 
 func NewRootCmd(entrypointFunc CobraRunFunc) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   appName,
 		Short: "CLI implementation for processing recipe JSON files",
-		Long:  "ivwCLI is a CLI tool enabling the processing or JSON data files and producing an aggregate report",
+		Long: "ivwCLI is a CLI tool enabling the processing or JSON data files and producing an aggregate report. " +
+			"gzip and zstd compressed files as well as tar archives are read directly",
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			logEnabled, _ := cmd.Flags().GetBool(logEnableFlag)
 			if logEnabled {
@@ -58,66 +119,289 @@ func NewRootCmd(entrypointFunc CobraRunFunc) *cobra.Command {
 		RunE:         entrypointFunc,
 		SilenceUsage: true,
 		Example: "ivwcli --file /tmp/file.json --match-recipes 'Speedy Steak Fajitas,Tex-Mex Tilapia' " +
-			"-o stdout -p 10120 --from 11AM --to 3PM",
+			"-o stdout -p 10120 --from 11AM --to 3PM\n" +
+			"ivwcli --file '/data/2023-01-*.json' --file /data/archive/ --per-file\n" +
+			"ivwcli --file /tmp/file.json --query 10120,10AM,3PM --query 10245,1PM,6PM --query-file queries.txt\n" +
+			"zcat /tmp/file.json.gz | ivwcli -p 10120",
 	}
 
 	cmd.Flags().BoolVarP(&LogEnabled, logEnableFlag, "l", false, "Enable logs")
-	cmd.Flags().StringVarP(&Filepath, filepathFlag, "f", "", "JSON file to process")
+	cmd.Flags().StringArrayVarP(&Filepaths, filepathFlag, "f", nil,
+		"JSON file, directory or glob pattern to process (can be repeated, all files are merged into one report). "+
+			"'-' or no file reads from piped stdin")
+	cmd.Flags().BoolVar(&PerFileBreakdown, perFileFlag, false, "Add a per file breakdown section to the report")
+	cmd.Flags().String(inputFormatFlag, string(processor.InputFormatAuto),
+		"Input file format (auto/json/ndjson), auto detects the format from the first non-whitespace character")
+	cmd.Flags().StringVar(&ArchiveMember, memberFlag, input.DefaultMemberPattern,
+		"Glob selecting the members processed when the input file is a tar archive (every match is processed)")
 	cmd.Flags().StringP(outputFlag, "o", "stdout", "Output path for result (file/STDOUT)")
+	cmd.Flags().String(formatFlag, string(model.FormatJSON),
+		"Report format (json/ndjson/yaml/csv/markdown/table/html), csv/markdown/table render every section as a table, "+
+			"html renders a self-contained page with charts")
+	cmd.Flags().String(templateFlag, "",
+		fmt.Sprintf("Render the report with a Go template file (*.html files with html/template) or a bundled template "+
+			"(%s) instead of --format", strings.Join(model.BuiltInTemplates(), "/")))
+	cmd.Flags().StringVar(&DLQPath, dlqFlag, "",
+		"Write rejected records with the reason they were rejected to this file (newline delimited JSON)")
+
+	cmd.Flags().Bool(strictFlag, false, "Abort on the first invalid record (same as --max-errors 0)")
+	cmd.Flags().Int(maxErrorsFlag, -1, "Abort once more than this number of records were rejected (-1 disables)")
+	cmd.Flags().Float64(maxErrorRateFlag, -1,
+		"Fail when the ratio (0 to 1) of rejected to read records exceeds this value (-1 disables)")
+
+	cmd.Flags().DurationVar(&Timeout, timeoutFlag, 0,
+		"Stop processing once this duration elapsed, e.g. 90s or 5m (0 disables)")
+	cmd.Flags().BoolVar(&PartialReport, partialFlag, false,
+		"Write the report of what was processed so far, marked as incomplete, on timeout or SIGINT/SIGTERM")
 
 	cmd.Flags().StringVarP(&Postcode, postcodeFlag, "p", "10120", "specific postcode to count")
+	cmd.Flags().IntVar(&TopPostcodes, topPostcodesFlag, 0,
+		"Rank the N busiest postcodes with their share of all deliveries in the report (0 disables)")
+	cmd.Flags().BoolVar(&PostcodeCounts, postcodeCntsFlag, false,
+		"Add the deliveries of every postcode to the report, so that reports can be merged exactly")
 	cmd.Flags().StringVar(&_deliveryFrom, deliveryFromFlag, "10AM", "set delivery start time for postcode count (inclusive)")
 	cmd.Flags().StringVar(&_deliveryTo, deliveryToFlag, "3PM", "set delivery end time for postcode count (inclusive)")
+	cmd.Flags().StringArrayVar(&_queries, queryFlag, nil,
+		"Additional postcode count as 'postcode,from,to' (can be repeated, all queries are counted in a single pass)")
+	cmd.Flags().StringVar(&_queryFile, queryFileFlag, "",
+		"File with one 'postcode,from,to' postcode count per line (blank lines and lines starting with # are skipped)")
+	cmd.Flags().StringSliceVar(&_days, daysFlag, nil,
+		"Only count deliveries on these weekdays for all the postcode counts, e.g. Mon,Tue (default every day)")
+	cmd.Flags().BoolVar(&PerWeekday, perWeekdayFlag, false,
+		"Add the deliveries and recipe counts per weekday to the report")
+	cmd.Flags().BoolVar(&HourlyLoad, hourlyFlag, false,
+		"Add the number of deliveries active during every hour of the day, overall and for the busiest postcodes")
+	cmd.Flags().BoolVar(&HourlyByWeekday, hourlyDaysFlag, false,
+		"Detail the hourly load per weekday (implies --"+hourlyFlag+")")
+	cmd.Flags().IntVar(&HourlyTop, hourlyTopFlag, 5,
+		"Number of busiest postcodes the hourly load is broken out for (implies --"+hourlyFlag+" when set)")
+	cmd.Flags().StringSlice(matchModeFlag, nil,
+		"When a delivery matches the timespan of a postcode count (contained/overlaps/starts-within/ends-within), "+
+			"several modes are reported side by side with their minutes of overlap (default contained)")
+	cmd.Flags().String(timeFormatFlag, string(model.TimeFormatRaw),
+		"How the times of the postcode counts are rendered in the report (raw/12h/24h), raw keeps them as passed")
+	cmd.Flags().BoolVar(&SingleQuery, singleQueryFlag, false,
+		"Output count_per_postcode_and_time as the object of the first query instead of an array of all the queries")
+
+	cmd.Flags().StringSliceVar(&Metrics, metricsFlag, nil,
+		"Metrics computed into the report, one of "+strings.Join(aggregate.RegisteredMetrics(), "/")+
+			" (default "+strings.Join(aggregate.DefaultMetrics, ",")+")")
 
 	cmd.Flags().StringSliceVarP(
 		&MatchRecipeTerms,
-		"match-recipes",
+		matchRecipesFlag,
 		"m",
 		[]string{"Potato", "Veggie", "Mushroom"},
 		"Match recipe names (comma separated)`",
 	)
-
-	cmd.MarkFlagRequired(filepathFlag)
+	cmd.Flags().String(recipeMatchFlag, string(aggregate.TermMatchSubstring),
+		"How the match terms match recipe names (substring/word/regex/glob/fuzzy), glob patterns match whole names")
+	cmd.Flags().Bool(ignoreCaseFlag, false, "Match recipe names regardless of case (Unicode case folding)")
+	cmd.Flags().Float64(fuzzyFlag, aggregate.DefaultFuzzyThreshold,
+		"Similarity (0 to 1) a recipe name must at least have with a term to match it in fuzzy mode")
 
 	return cmd
 }
 
-// MustCli instantiates the CLI with the given entrypoint function passed to it
-func MustCli(rootCmd *cobra.Command) {
-	if err := rootCmd.Execute(); err != nil {
-		panic(err)
-	}
-}
-
 // validateFlags validates values passed to flags
 func validateFlags(cmd *cobra.Command) error {
-	err := validateOutputFlag(cmd)
+	err := validateFileFlag()
+	if err != nil {
+		return err
+	}
+	err = validateOutputFlag(cmd)
 	if err != nil {
 		return err
 	}
-	return validateDeliveryFlags(cmd)
+	err = validateInputFormatFlag(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateFormatFlag(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateTemplateFlag(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateTimeFormatFlag(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateMatchModeFlag(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateRecipeMatchFlags(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateErrorBudgetFlags(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateTimeoutFlag()
+	if err != nil {
+		return err
+	}
+	if TopPostcodes < 0 {
+		return fmt.Errorf("invalid %s %d, must not be negative", topPostcodesFlag, TopPostcodes)
+	}
+	err = validateHourlyLoadFlags(cmd)
+	if err != nil {
+		return err
+	}
+	err = validateMetricsFlag()
+	if err != nil {
+		return err
+	}
+	return validateQueryFlags(cmd)
+}
+
+// validateMetricsFlag validates that all the metrics are registered
+func validateMetricsFlag() error {
+	for _, name := range Metrics {
+		if !aggregate.IsRegisteredMetric(name) {
+			return fmt.Errorf("invalid %s %q, must be one of %s", metricsFlag, name,
+				strings.Join(aggregate.RegisteredMetrics(), "/"))
+		}
+	}
+	return nil
+}
+
+// validateHourlyLoadFlags enables the hourly load when any of its flags is passed
+func validateHourlyLoadFlags(cmd *cobra.Command) error {
+	if HourlyTop < 0 {
+		return fmt.Errorf("invalid %s %d, must not be negative", hourlyTopFlag, HourlyTop)
+	}
+	if HourlyByWeekday || cmd.Flags().Changed(hourlyTopFlag) {
+		HourlyLoad = true
+	}
+	return nil
 }
 
-// validateDeliveryFlags validates that the delivery flags are passed in correct format. additionally, the timespan
-// passed must occur in the same 24hour period, for example 3AM to 1PM, NOT 8PM to 2AM
-func validateDeliveryFlags(cmd *cobra.Command) (err error) {
-	from, _ := cmd.Flags().GetString(deliveryFromFlag)
-	to, _ := cmd.Flags().GetString(deliveryToFlag)
+// validateFileFlag defaults to reading stdin when no file is passed, as long as data is piped into stdin
+func validateFileFlag() error {
+	if len(Filepaths) > 0 {
+		return nil
+	}
+	if stdinIsTerminal() {
+		return fmt.Errorf("required flag \"%s\" not set and no data is piped through stdin", filepathFlag)
+	}
+	Filepaths = []string{input.StdinPath}
+	return nil
+}
 
-	DeliveryFrom, err = model.NewDeliveryTime(from)
+// validateInputFormatFlag validates that the input format is one of the supported formats
+func validateInputFormatFlag(cmd *cobra.Command) (err error) {
+	val, _ := cmd.Flags().GetString(inputFormatFlag)
+	InputFormat, err = processor.ParseInputFormat(val)
+	return err
+}
+
+// validateFormatFlag validates that the report format is one of the supported formats, the html format ranks the top
+// postcodes unless --top-postcodes is passed
+func validateFormatFlag(cmd *cobra.Command) (err error) {
+	val, _ := cmd.Flags().GetString(formatFlag)
+	Format, err = model.ParseFormat(val)
 	if err != nil {
 		return err
 	}
-	DeliveryTo, err = model.NewDeliveryTime(to)
+	// the html report charts the top postcodes
+	if Format == model.FormatHTML && !cmd.Flags().Changed(topPostcodesFlag) {
+		TopPostcodes = aggregate.DefaultTopPostcodes
+	}
+	Renderer = model.NewRenderer(Format)
+	return nil
+}
+
+// validateTemplateFlag parses the template, so that a missing file or a syntax error is reported before any input is
+// processed. the template replaces the renderer of --format, passing both is ambiguous
+func validateTemplateFlag(cmd *cobra.Command) (err error) {
+	val, _ := cmd.Flags().GetString(templateFlag)
+	if val == "" {
+		return nil
+	}
+	if cmd.Flags().Changed(formatFlag) {
+		return fmt.Errorf("--%s and --%s are mutually exclusive", templateFlag, formatFlag)
+	}
+	Renderer, err = model.NewTemplateRenderer(val)
+	return err
+}
+
+// validateTimeFormatFlag validates that the time format is one of the supported formats
+func validateTimeFormatFlag(cmd *cobra.Command) (err error) {
+	val, _ := cmd.Flags().GetString(timeFormatFlag)
+	TimeFormat, err = model.ParseTimeFormat(val)
+	return err
+}
+
+// validateMatchModeFlag validates that every match mode is supported, the first mode decides the delivery count of
+// the postcode counts
+func validateMatchModeFlag(cmd *cobra.Command) error {
+	values, _ := cmd.Flags().GetStringSlice(matchModeFlag)
+
+	MatchModes = nil
+	for _, value := range values {
+		mode, err := model.ParseMatchMode(value)
+		if err != nil {
+			return err
+		}
+		MatchModes = append(MatchModes, mode)
+	}
+	return nil
+}
+
+// validateRecipeMatchFlags compiles the match terms according to the recipe match mode, so that invalid regex or glob
+// terms are reported before any input is processed. the fuzzy threshold only applies to the fuzzy mode
+func validateRecipeMatchFlags(cmd *cobra.Command) error {
+	val, _ := cmd.Flags().GetString(recipeMatchFlag)
+	mode, err := aggregate.ParseTermMatchMode(val)
 	if err != nil {
 		return err
 	}
+	ignoreCase, _ := cmd.Flags().GetBool(ignoreCaseFlag)
+	threshold, _ := cmd.Flags().GetFloat64(fuzzyFlag)
+	if threshold <= 0 || threshold > 1 {
+		return fmt.Errorf("invalid %s %v, must be above 0 and at most 1", fuzzyFlag, threshold)
+	}
+
+	RecipeMatcher, err = aggregate.NewTermMatcher(MatchRecipeTerms, mode, ignoreCase)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", matchRecipesFlag, err)
+	}
+	RecipeMatcher.SetFuzzyThreshold(threshold)
+	return nil
+}
+
+// validateErrorBudgetFlags validates the error budget thresholds, a negative value disables a threshold. strict mode
+// tolerates no rejected record at all
+func validateErrorBudgetFlags(cmd *cobra.Command) error {
+	strict, _ := cmd.Flags().GetBool(strictFlag)
+	maxErrors, _ := cmd.Flags().GetInt(maxErrorsFlag)
+	maxErrorRate, _ := cmd.Flags().GetFloat64(maxErrorRateFlag)
 
-	if DeliveryTo.Before(DeliveryFrom.Time) {
-		return fmt.Errorf("invalid delivery time (%s - %s) delivery times are not date scoped,"+
-			" 'to' must occur before 'from' time", from, to)
+	if maxErrorRate > 1 {
+		return fmt.Errorf("invalid %s %v, must be between 0 and 1 (or negative to disable)", maxErrorRateFlag,
+			maxErrorRate)
+	}
+	if maxErrors < 0 {
+		maxErrors = -1
+	}
+	if strict {
+		maxErrors = 0
 	}
 
+	ErrorBudget = processor.ErrorBudget{MaxErrors: maxErrors, MaxErrorRate: maxErrorRate}
+	return nil
+}
+
+// validateTimeoutFlag validates that the timeout is not negative, a timeout of 0 disables it
+func validateTimeoutFlag() error {
+	if Timeout < 0 {
+		return fmt.Errorf("invalid %s %s, must not be negative", timeoutFlag, Timeout)
+	}
 	return nil
 }
 
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"errors"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -10,9 +9,10 @@ import (
 func TestNewRootCmd(t *testing.T) {
 
 	tcs := []struct {
-		name     string
-		setFlags func(*cobra.Command)
-		wantErr  bool
+		name       string
+		setFlags   func(*cobra.Command)
+		stdinPiped bool
+		wantErr    bool
 	}{
 		{
 			name: "happy path",
@@ -28,6 +28,21 @@ func TestNewRootCmd(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "missing file flag with piped stdin",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{})
+			},
+			stdinPiped: true,
+			wantErr:    false,
+		},
+		{
+			name: "reading stdin explicitly",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "-"})
+			},
+			wantErr: false,
+		},
 		{
 			name: "passing invalid delivery times",
 			setFlags: func(cmd *cobra.Command) {
@@ -35,11 +50,129 @@ func TestNewRootCmd(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "passing multiple files",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "a.json", "-f", "/data/*.json", "--per-file"})
+			},
+			wantErr: false,
+		},
+		{
+			name: "passing invalid input format",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--input-format", "csv"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing invalid max error rate",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--max-error-rate", "1.5"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing negative timeout",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--timeout", "-1s"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing invalid time format",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--time-format", "iso"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing invalid match mode",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--match-mode", "contained,around"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing negative hourly load postcodes",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--hourly-load-top-postcodes", "-1"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing unknown metric",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--metrics", "busiest_postcode,busiest_recipe"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing invalid recipe match mode",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--recipe-match-mode", "exact"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing invalid regex term",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--recipe-match-mode", "regex", "-m", "Potato,(Veg"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing invalid fuzzy threshold",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--recipe-match-mode", "fuzzy", "--fuzzy-threshold", "0"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing unsupported format",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--format", "xml"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing html format",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--format", "html"})
+			},
+			wantErr: false,
+		},
+		{
+			name: "passing template and format",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--format", "csv", "--template", "summary.md"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing missing template",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--template", "/tmp/missing-report.tmpl"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "passing bundled template",
+			setFlags: func(cmd *cobra.Command) {
+				cmd.SetArgs([]string{"--file", "stdout", "--template", "report.html"})
+			},
+			wantErr: false,
+		},
 		{
 			name: "passing all the flags",
 			setFlags: func(cmd *cobra.Command) {
 				cmd.SetArgs([]string{"--file", "stdout", "-l", "-p", "10245", "--from", "1PM",
-					"--to", "6PM", "-o", "stdout"})
+					"--to", "6PM", "-o", "stdout", "--input-format", "ndjson", "--strict", "--max-error-rate", "0.1",
+					"--timeout", "5m", "--partial-report", "--top-postcodes", "5",
+					"--time-format", "24h", "--match-mode", "overlaps,contained",
+					"--hourly-load-by-weekday", "--hourly-load-top-postcodes", "3",
+					"--metrics", "unique_recipe_count,busiest_postcode",
+					"-m", "^Potato,Veg(gie)?$", "--recipe-match-mode", "regex", "--ignore-case",
+					"--fuzzy-threshold", "0.7", "--format", "markdown", "--postcode-counts"})
 			},
 			wantErr: false,
 		},
@@ -47,6 +180,9 @@ func TestNewRootCmd(t *testing.T) {
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
+			stdinIsTerminal = func() bool {
+				return !tc.stdinPiped
+			}
 			cmd := NewRootCmd(func(cmd *cobra.Command, args []string) error {
 				return nil
 			})
@@ -62,42 +198,89 @@ func TestNewRootCmd(t *testing.T) {
 	}
 }
 
-func TestMustCli(t *testing.T) {
+func TestNewDiffCmd(t *testing.T) {
 	tcs := []struct {
-		name   string
-		cmd    *cobra.Command
-		panics bool
+		name    string
+		args    []string
+		wantErr bool
 	}{
 		{
-			name: "happy path",
-			cmd: &cobra.Command{
-				RunE: func(cmd *cobra.Command, args []string) error {
-					return nil
-				},
-			},
-			panics: false,
+			name:    "happy path",
+			args:    []string{"old.json", "new.json"},
+			wantErr: false,
 		},
 		{
-			name: "happy path",
-			cmd: &cobra.Command{
-				RunE: func(cmd *cobra.Command, args []string) error {
-					return errors.New("oh noes")
-				},
-			},
-			panics: true,
+			name:    "json format with change threshold",
+			args:    []string{"--format", "json", "--max-changes", "0", "old.json", "new.json"},
+			wantErr: false,
+		},
+		{
+			name:    "missing report",
+			args:    []string{"old.json"},
+			wantErr: true,
+		},
+		{
+			name:    "passing unsupported format",
+			args:    []string{"--format", "html", "old.json", "new.json"},
+			wantErr: true,
+		},
+		{
+			name:    "passing invalid change threshold",
+			args:    []string{"--max-changes", "-2", "old.json", "new.json"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := NewDiffCmd(func(cmd *cobra.Command, args []string) error {
+				return nil
+			})
+			cmd.SetArgs(tc.args)
+
+			if tc.wantErr {
+				assert.NotNil(t, cmd.Execute())
+			} else {
+				assert.Nil(t, cmd.Execute())
+			}
+		})
+	}
+}
+
+func TestNewMergeCmd(t *testing.T) {
+	tcs := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name:    "happy path",
+			args:    []string{"r1.json", "r2.json", "r3.json"},
+			wantErr: false,
+		},
+		{
+			name:    "single report",
+			args:    []string{"r1.json"},
+			wantErr: true,
+		},
+		{
+			name:    "unwritable output",
+			args:    []string{"-o", "/nonexistent/merged.json", "r1.json", "r2.json"},
+			wantErr: true,
 		},
 	}
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
-			if tc.panics {
-				assert.Panics(t, func() {
-					MustCli(tc.cmd)
-				})
+			cmd := NewMergeCmd(func(cmd *cobra.Command, args []string) error {
+				return nil
+			})
+			cmd.SetArgs(tc.args)
+
+			if tc.wantErr {
+				assert.NotNil(t, cmd.Execute())
 			} else {
-				assert.NotPanics(t, func() {
-					MustCli(tc.cmd)
-				})
+				assert.Nil(t, cmd.Execute())
 			}
 		})
 	}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// DiffFormat is an output format of the diff command
+type DiffFormat string
+
+const (
+	DiffFormatText DiffFormat = "text"
+	DiffFormatJSON DiffFormat = "json"
+)
+
+// Diff flags
+var (
+	DiffOutput DiffFormat
+	MaxChanges int
+)
+
+// Diff flag names
+const (
+	diffFormatFlag = "format"
+	maxChangesFlag = "max-changes"
+)
+
+// NewDiffCmd returns the diff command comparing two reports written as JSON
+func NewDiffCmd(entrypointFunc CobraRunFunc) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff old.json new.json",
+		Short: "Show what changed between two JSON reports",
+		Long: "diff compares two reports written with --format json and shows the recipes that appeared or " +
+			"disappeared, the count deltas per recipe and the changes of the busiest postcode, the postcode counts " +
+			"and the matched recipes. it fails when the number of changes exceeds --max-changes",
+		Args: cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateDiffFlags(cmd)
+		},
+		RunE:         entrypointFunc,
+		SilenceUsage: true,
+		Example: "ivwcli diff reports/2023-01-01.json reports/2023-01-02.json\n" +
+			"ivwcli diff --format json --max-changes 10 old.json new.json",
+	}
+
+	cmd.Flags().String(diffFormatFlag, string(DiffFormatText), "Diff format (text/json)")
+	cmd.Flags().IntVar(&MaxChanges, maxChangesFlag, -1,
+		"Fail once more than this number of entries changed (-1 disables)")
+
+	return cmd
+}
+
+// validateDiffFlags validates that the diff format is supported and that the change threshold is not below -1
+func validateDiffFlags(cmd *cobra.Command) error {
+	val, _ := cmd.Flags().GetString(diffFormatFlag)
+	switch format := DiffFormat(strings.ToLower(val)); format {
+	case DiffFormatText, DiffFormatJSON:
+		DiffOutput = format
+	default:
+		return fmt.Errorf("unsupported diff format %q, must be one of [%s, %s]", val, DiffFormatText, DiffFormatJSON)
+	}
+
+	if MaxChanges < -1 {
+		return fmt.Errorf("invalid %s %d, must be -1 or more", maxChangesFlag, MaxChanges)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewMergeCmd returns the merge command combining reports computed over partitions of the input into a single report
+func NewMergeCmd(entrypointFunc CobraRunFunc) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "merge r1.json r2.json ...",
+		Short: "Merge JSON reports of input partitions into the report of the whole input",
+		Long: "merge combines reports written with --format json over distinct partitions of the input: recipe, " +
+			"postcode/time-window, weekday and hourly counts are summed, the unique recipe count and the matches are " +
+			"recomputed. the busiest and top postcodes are only merged when every report was written with " +
+			"--postcode-counts. the merged report is written as JSON, so that it can be merged again",
+		Args: cobra.MinimumNArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutputFlag(cmd)
+		},
+		RunE:         entrypointFunc,
+		SilenceUsage: true,
+		Example: "ivwcli merge -o report.json partitions/part-0.json partitions/part-1.json\n" +
+			"ivwcli merge partitions/*.json",
+	}
+
+	cmd.Flags().StringP(outputFlag, "o", "stdout", "Output path for the merged report (file/STDOUT)")
+
+	return cmd
+}
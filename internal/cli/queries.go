@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/davido912-recipe-count-test-2020/internal/aggregate"
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/spf13/cobra"
+)
+
+// validateQueryFlags builds the postcode queries from the --query and --query-file flags. the query of the
+// -p/--from/--to flags is counted first, unless only --query or --query-file were passed. --days limits all the
+// queries to the given weekdays
+func validateQueryFlags(cmd *cobra.Command) error {
+	Queries = nil
+
+	flags := cmd.Flags()
+	explicitQueries := flags.Changed(queryFlag) || flags.Changed(queryFileFlag)
+	if !explicitQueries || flags.Changed(postcodeFlag) || flags.Changed(deliveryFromFlag) ||
+		flags.Changed(deliveryToFlag) {
+		query, err := validateDeliveryFlags(Postcode, _deliveryFrom, _deliveryTo)
+		if err != nil {
+			return err
+		}
+		Queries = append(Queries, query)
+	}
+
+	for _, value := range _queries {
+		query, err := parseQuery(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", queryFlag, err)
+		}
+		Queries = append(Queries, query)
+	}
+
+	if _queryFile != "" {
+		fileQueries, err := readQueryFile(_queryFile)
+		if err != nil {
+			return err
+		}
+		Queries = append(Queries, fileQueries...)
+	}
+
+	days, err := parseDays(_days)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", daysFlag, err)
+	}
+	for i := range Queries {
+		Queries[i].Days = days
+	}
+
+	return nil
+}
+
+// parseDays parses day names into weekdays, ordered from Monday to Sunday without duplicates
+func parseDays(values []string) ([]time.Weekday, error) {
+	selected := make(map[time.Weekday]bool, len(values))
+	for _, value := range values {
+		weekday, err := model.ParseWeekday(value)
+		if err != nil {
+			return nil, err
+		}
+		selected[weekday] = true
+	}
+
+	var days []time.Weekday
+	for _, weekday := range model.Weekdays {
+		if selected[weekday] {
+			days = append(days, weekday)
+		}
+	}
+	return days, nil
+}
+
+// readQueryFile reads one 'postcode,from,to' query per line, blank lines and lines starting with # are skipped
+func readQueryFile(path string) ([]aggregate.PostcodeQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var queries []aggregate.PostcodeQuery
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		query, err := parseQuery(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query in %s line %d: %w", path, lineNum, err)
+		}
+		queries = append(queries, query)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading %s: %w", path, err)
+	}
+
+	return queries, nil
+}
+
+// parseQuery parses a query in the 'postcode,from,to' format, e.g. 10120,10AM,3PM
+func parseQuery(value string) (aggregate.PostcodeQuery, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return aggregate.PostcodeQuery{}, fmt.Errorf("%q must be in the format postcode,from,to", value)
+	}
+
+	return validateDeliveryFlags(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), strings.TrimSpace(parts[2]))
+}
+
+// validateDeliveryFlags validates that the delivery times are passed in correct format. a 'to' time before the 'from'
+// time makes an overnight timespan ending on the next day, for example 8PM to 2AM
+func validateDeliveryFlags(postcode, from, to string) (aggregate.PostcodeQuery, error) {
+	if postcode == "" {
+		return aggregate.PostcodeQuery{}, fmt.Errorf("postcode must not be blank")
+	}
+
+	deliveryFrom, err := model.NewDeliveryTime(from)
+	if err != nil {
+		return aggregate.PostcodeQuery{}, err
+	}
+	deliveryTo, err := model.NewDeliveryTime(to)
+	if err != nil {
+		return aggregate.PostcodeQuery{}, err
+	}
+
+	return aggregate.PostcodeQuery{Postcode: postcode, DeliveryFrom: deliveryFrom, DeliveryTo: deliveryTo}, nil
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateQueryFlags(t *testing.T) {
+	queryFile := filepath.Join(t.TempDir(), "queries.txt")
+	err := os.WriteFile(queryFile, []byte("# postcode,from,to\n10311,1PM,6PM\n\n 10342 , 8AM , 11AM \n"), 0644)
+	assert.Nil(t, err)
+
+	invalidQueryFile := filepath.Join(t.TempDir(), "invalid.txt")
+	err = os.WriteFile(invalidQueryFile, []byte("10311,1PM,6PM\n10342,11AM\n"), 0644)
+	assert.Nil(t, err)
+
+	tcs := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "default query",
+			args: []string{},
+			want: []string{"10120 10AM-3PM"},
+		},
+		{
+			name: "repeated queries replace the default query",
+			args: []string{"--query", "10245,1PM,6PM", "--query", "10311,10AM,11AM"},
+			want: []string{"10245 1PM-6PM", "10311 10AM-11AM"},
+		},
+		{
+			name: "explicit postcode query is counted first",
+			args: []string{"-p", "10224", "--query", "10245,1PM,6PM"},
+			want: []string{"10224 10AM-3PM", "10245 1PM-6PM"},
+		},
+		{
+			name: "query file",
+			args: []string{"--query", "10245,1PM,6PM", "--query-file", queryFile},
+			want: []string{"10245 1PM-6PM", "10311 1PM-6PM", "10342 8AM-11AM"},
+		},
+		{
+			name: "days limit all the queries",
+			args: []string{"--query", "10245,1PM,6PM", "--query", "10311,10AM,11AM", "--days", "tue,Monday,Tue"},
+			want: []string{"10245 1PM-6PM Monday,Tuesday", "10311 10AM-11AM Monday,Tuesday"},
+		},
+		{
+			name:    "invalid day",
+			args:    []string{"--days", "Mon,Someday"},
+			wantErr: true,
+		},
+		{
+			name:    "query missing a field",
+			args:    []string{"--query", "10245,1PM"},
+			wantErr: true,
+		},
+		{
+			name:    "query with invalid time",
+			args:    []string{"--query", "10245,1PM,25PM"},
+			wantErr: true,
+		},
+		{
+			name: "overnight query",
+			args: []string{"--query", "10245,8PM,2AM", "--days", "Fri"},
+			want: []string{"10245 8PM-2AM Friday"},
+		},
+		{
+			name:    "invalid query file",
+			args:    []string{"--query-file", invalidQueryFile},
+			wantErr: true,
+		},
+		{
+			name:    "missing query file",
+			args:    []string{"--query-file", filepath.Join(t.TempDir(), "missing.txt")},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := NewRootCmd(func(cmd *cobra.Command, args []string) error {
+				return nil
+			})
+			assert.Nil(t, cmd.ParseFlags(tc.args))
+
+			err := validateQueryFlags(cmd)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+
+			var got []string
+			for _, query := range Queries {
+				queryStr := query.Postcode + " " + query.DeliveryFrom.Raw() + "-" + query.DeliveryTo.Raw()
+				var days []string
+				for _, day := range query.Days {
+					days = append(days, day.String())
+				}
+				if len(days) > 0 {
+					queryStr += " " + strings.Join(days, ",")
+				}
+				got = append(got, queryStr)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+)
+
+// Status tells how an entry changed between the old and the new report
+type Status string
+
+const (
+	StatusAdded   Status = "added"
+	StatusRemoved Status = "removed"
+	StatusChanged Status = "changed"
+)
+
+// statusSymbols prefix the entries of the text output
+var statusSymbols = map[Status]string{StatusAdded: "+", StatusRemoved: "-", StatusChanged: "~"}
+
+type (
+	// CountChange is a count of the old and the new report, Delta is new - old
+	CountChange struct {
+		Old   int `json:"old"`
+		New   int `json:"new"`
+		Delta int `json:"delta"`
+	}
+
+	// RecipeChange is a recipe that appeared, disappeared or whose count changed
+	RecipeChange struct {
+		Recipe string `json:"recipe"`
+		Status Status `json:"status"`
+		CountChange
+	}
+
+	// PostcodeChange is the busiest postcode of the old and the new report
+	PostcodeChange struct {
+		Old model.PostcodeCount `json:"old"`
+		New model.PostcodeCount `json:"new"`
+	}
+
+	// QueryChange is a postcode/time-window count that appeared, disappeared or whose count changed. counts are told
+	// apart by their postcode, window and days
+	QueryChange struct {
+		Postcode string   `json:"postcode"`
+		From     string   `json:"from"`
+		To       string   `json:"to"`
+		Days     []string `json:"days,omitempty"`
+		Status   Status   `json:"status"`
+		CountChange
+	}
+
+	// MatchChange lists the recipe names that were added to and removed from the match list
+	MatchChange struct {
+		Added   []string `json:"added"`
+		Removed []string `json:"removed"`
+	}
+
+	// Diff details what changed between two reports. sections omitted from either report are not compared
+	Diff struct {
+		UniqueRecipeCount       *CountChange    `json:"unique_recipe_count,omitempty"`
+		Recipes                 []RecipeChange  `json:"recipes"`
+		BusiestPostcode         *PostcodeChange `json:"busiest_postcode,omitempty"`
+		CountPerPostcodeAndTime []QueryChange   `json:"count_per_postcode_and_time"`
+		MatchByName             MatchChange     `json:"match_by_name"`
+		Changes                 int             `json:"changes"`
+	}
+)
+
+func newCountChange(oldCount, newCount int) CountChange {
+	return CountChange{Old: oldCount, New: newCount, Delta: newCount - oldCount}
+}
+
+// Compare returns what changed from oldReport to newReport. Changes counts the changed recipes, postcode counts and
+// matches, plus one each when the unique recipe count or the busiest postcode changed
+func Compare(oldReport, newReport *model.ReportModel) *Diff {
+	d := &Diff{
+		Recipes:                 []RecipeChange{},
+		CountPerPostcodeAndTime: []QueryChange{},
+		MatchByName:             MatchChange{Added: []string{}, Removed: []string{}},
+	}
+
+	compared := func(section string) bool {
+		return !oldReport.Omitted(section) && !newReport.Omitted(section)
+	}
+
+	if compared(model.SectionUniqueRecipeCount) && oldReport.UniqueRecipeCount != newReport.UniqueRecipeCount {
+		change := newCountChange(oldReport.UniqueRecipeCount, newReport.UniqueRecipeCount)
+		d.UniqueRecipeCount = &change
+		d.Changes++
+	}
+	if compared(model.SectionCountPerRecipe) {
+		d.Recipes = compareRecipes(oldReport.CountPerRecipe, newReport.CountPerRecipe)
+	}
+	if compared(model.SectionBusiestPostcode) && oldReport.BusiestPostcode != newReport.BusiestPostcode {
+		d.BusiestPostcode = &PostcodeChange{Old: oldReport.BusiestPostcode, New: newReport.BusiestPostcode}
+		d.Changes++
+	}
+	if compared(model.SectionCountPerPostcodeAndTime) {
+		d.CountPerPostcodeAndTime = compareQueries(oldReport.CountPerPostcodeAndTime, newReport.CountPerPostcodeAndTime)
+	}
+	if compared(model.SectionMatchByName) {
+		d.MatchByName = compareMatches(oldReport.MatchByName, newReport.MatchByName)
+	}
+
+	d.Changes += len(d.Recipes) + len(d.CountPerPostcodeAndTime) + len(d.MatchByName.Added) +
+		len(d.MatchByName.Removed)
+	return d
+}
+
+// compareRecipes returns the recipes whose count changed, sorted by recipe name
+func compareRecipes(oldCounts, newCounts model.RecipeCounts) []RecipeChange {
+	counts := make(map[string]*RecipeChange, len(oldCounts)+len(newCounts))
+	for _, recipeCount := range oldCounts {
+		counts[recipeCount.Recipe] = &RecipeChange{Recipe: recipeCount.Recipe, Status: StatusRemoved,
+			CountChange: newCountChange(recipeCount.RecipeCount, 0)}
+	}
+	for _, recipeCount := range newCounts {
+		change, ok := counts[recipeCount.Recipe]
+		if !ok {
+			counts[recipeCount.Recipe] = &RecipeChange{Recipe: recipeCount.Recipe, Status: StatusAdded,
+				CountChange: newCountChange(0, recipeCount.RecipeCount)}
+			continue
+		}
+		change.Status = StatusChanged
+		change.CountChange = newCountChange(change.Old, recipeCount.RecipeCount)
+	}
+
+	changes := make([]RecipeChange, 0)
+	for _, change := range counts {
+		if change.Status == StatusChanged && change.Delta == 0 {
+			continue
+		}
+		changes = append(changes, *change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Recipe < changes[j].Recipe
+	})
+	return changes
+}
+
+// queryKey tells postcode counts apart
+func queryKey(query model.PostcodeTimeCount) string {
+	return strings.Join([]string{query.Postcode, query.From, query.To, strings.Join(query.Days, ",")}, "|")
+}
+
+// compareQueries returns the postcode counts whose count changed, in the order of the new report followed by the
+// counts the new report does not have
+func compareQueries(oldQueries, newQueries []model.PostcodeTimeCount) []QueryChange {
+	oldCounts := make(map[string]int, len(oldQueries))
+	for _, query := range oldQueries {
+		oldCounts[queryKey(query)] = query.DeliveryCount
+	}
+
+	changes := make([]QueryChange, 0)
+	seen := make(map[string]bool, len(newQueries))
+	for _, query := range newQueries {
+		key := queryKey(query)
+		seen[key] = true
+		change := QueryChange{Postcode: query.Postcode, From: query.From, To: query.To, Days: query.Days}
+
+		oldCount, ok := oldCounts[key]
+		switch {
+		case !ok:
+			change.Status = StatusAdded
+		case oldCount != query.DeliveryCount:
+			change.Status = StatusChanged
+		default:
+			continue
+		}
+		change.CountChange = newCountChange(oldCount, query.DeliveryCount)
+		changes = append(changes, change)
+	}
+
+	for _, query := range oldQueries {
+		if seen[queryKey(query)] {
+			continue
+		}
+		changes = append(changes, QueryChange{Postcode: query.Postcode, From: query.From, To: query.To,
+			Days: query.Days, Status: StatusRemoved, CountChange: newCountChange(query.DeliveryCount, 0)})
+	}
+	return changes
+}
+
+// compareMatches returns the recipe names added to and removed from the match list, sorted by name
+func compareMatches(oldMatches, newMatches model.RecipeMatches) MatchChange {
+	change := MatchChange{Added: difference(newMatches, oldMatches), Removed: difference(oldMatches, newMatches)}
+	sort.Strings(change.Added)
+	sort.Strings(change.Removed)
+	return change
+}
+
+// difference returns the names of a that are not in b
+func difference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, name := range b {
+		inB[name] = true
+	}
+	names := make([]string, 0)
+	for _, name := range a {
+		if !inB[name] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// WriteText writes the changes for reading in a terminal, every changed entry on its own line prefixed by + (added),
+// - (removed) or ~ (changed)
+func (d *Diff) WriteText(out io.Writer) error {
+	if d.Changes == 0 {
+		_, err := fmt.Fprintln(out, "no changes")
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if d.UniqueRecipeCount != nil {
+		fmt.Fprintf(w, "unique recipe count: %s\n", d.UniqueRecipeCount)
+	}
+	if len(d.Recipes) > 0 {
+		fmt.Fprintln(w, "count per recipe:")
+		for _, change := range d.Recipes {
+			fmt.Fprintf(w, "  %s %s\t%s\n", statusSymbols[change.Status], change.Recipe, change.CountChange)
+		}
+	}
+	if d.BusiestPostcode != nil {
+		fmt.Fprintf(w, "busiest postcode: %s (%d) -> %s (%d)\n", d.BusiestPostcode.Old.Postcode,
+			d.BusiestPostcode.Old.DeliveryCount, d.BusiestPostcode.New.Postcode, d.BusiestPostcode.New.DeliveryCount)
+	}
+	if len(d.CountPerPostcodeAndTime) > 0 {
+		fmt.Fprintln(w, "count per postcode and time:")
+		for _, change := range d.CountPerPostcodeAndTime {
+			window := fmt.Sprintf("%s %s - %s", change.Postcode, change.From, change.To)
+			if len(change.Days) > 0 {
+				window += " " + strings.Join(change.Days, ",")
+			}
+			fmt.Fprintf(w, "  %s %s\t%s\n", statusSymbols[change.Status], window, change.CountChange)
+		}
+	}
+	if len(d.MatchByName.Added)+len(d.MatchByName.Removed) > 0 {
+		fmt.Fprintln(w, "match by name:")
+		for _, name := range d.MatchByName.Added {
+			fmt.Fprintf(w, "  %s %s\n", statusSymbols[StatusAdded], name)
+		}
+		for _, name := range d.MatchByName.Removed {
+			fmt.Fprintf(w, "  %s %s\n", statusSymbols[StatusRemoved], name)
+		}
+	}
+	fmt.Fprintf(w, "%d changes\n", d.Changes)
+	return w.Flush()
+}
+
+// String renders the change as "old -> new (delta)"
+func (c CountChange) String() string {
+	return fmt.Sprintf("%d -> %d (%+d)", c.Old, c.New, c.Delta)
+}
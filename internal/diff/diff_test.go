@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func mockReports() (*model.ReportModel, *model.ReportModel) {
+	oldReport := model.NewReportModel()
+	oldReport.SetUniqueRecipeCount(3)
+	oldReport.SetCountPerRecipe(model.RecipeCounts{
+		{Recipe: "Pear", RecipeCount: 1},
+		{Recipe: "Salt", RecipeCount: 2},
+		{Recipe: "Steak", RecipeCount: 4},
+	})
+	oldReport.SetBusiestPostcode(model.PostcodeCount{Postcode: "10120", DeliveryCount: 3})
+	oldReport.SetCountPerPostcodeAndTime([]model.PostcodeTimeCount{
+		{Postcode: "10120", From: "10AM", To: "3PM", DeliveryCount: 4},
+		{Postcode: "10245", From: "1PM", To: "6PM", DeliveryCount: 2},
+	})
+	oldReport.SetMatchByName(model.RecipeMatches{"Pear", "Steak"})
+
+	newReport := model.NewReportModel()
+	newReport.SetUniqueRecipeCount(3)
+	newReport.SetCountPerRecipe(model.RecipeCounts{
+		{Recipe: "Apple", RecipeCount: 2},
+		{Recipe: "Salt", RecipeCount: 2},
+		{Recipe: "Steak", RecipeCount: 7},
+	})
+	newReport.SetBusiestPostcode(model.PostcodeCount{Postcode: "10245", DeliveryCount: 5})
+	newReport.SetCountPerPostcodeAndTime([]model.PostcodeTimeCount{
+		{Postcode: "10120", From: "10AM", To: "3PM", DeliveryCount: 6},
+		{Postcode: "10120", From: "10AM", To: "3PM", Days: []string{"Monday"}, DeliveryCount: 1},
+	})
+	newReport.SetMatchByName(model.RecipeMatches{"Apple", "Steak"})
+
+	return oldReport, newReport
+}
+
+func TestCompare(t *testing.T) {
+	oldReport, newReport := mockReports()
+
+	want := &Diff{
+		Recipes: []RecipeChange{
+			{Recipe: "Apple", Status: StatusAdded, CountChange: CountChange{Old: 0, New: 2, Delta: 2}},
+			{Recipe: "Pear", Status: StatusRemoved, CountChange: CountChange{Old: 1, New: 0, Delta: -1}},
+			{Recipe: "Steak", Status: StatusChanged, CountChange: CountChange{Old: 4, New: 7, Delta: 3}},
+		},
+		BusiestPostcode: &PostcodeChange{
+			Old: model.PostcodeCount{Postcode: "10120", DeliveryCount: 3},
+			New: model.PostcodeCount{Postcode: "10245", DeliveryCount: 5},
+		},
+		CountPerPostcodeAndTime: []QueryChange{
+			{Postcode: "10120", From: "10AM", To: "3PM", Status: StatusChanged,
+				CountChange: CountChange{Old: 4, New: 6, Delta: 2}},
+			{Postcode: "10120", From: "10AM", To: "3PM", Days: []string{"Monday"}, Status: StatusAdded,
+				CountChange: CountChange{Old: 0, New: 1, Delta: 1}},
+			{Postcode: "10245", From: "1PM", To: "6PM", Status: StatusRemoved,
+				CountChange: CountChange{Old: 2, New: 0, Delta: -2}},
+		},
+		MatchByName: MatchChange{Added: []string{"Apple"}, Removed: []string{"Pear"}},
+		Changes:     9,
+	}
+
+	assert.Equal(t, want, Compare(oldReport, newReport))
+}
+
+func TestCompare_SameReport(t *testing.T) {
+	oldReport, _ := mockReports()
+
+	got := Compare(oldReport, oldReport)
+
+	assert.Equal(t, 0, got.Changes)
+	assert.Nil(t, got.UniqueRecipeCount)
+	assert.Nil(t, got.BusiestPostcode)
+}
+
+func TestCompare_OmittedSections(t *testing.T) {
+	oldReport, newReport := mockReports()
+	newReport.Omit(model.SectionBusiestPostcode)
+	oldReport.Omit(model.SectionCountPerRecipe)
+
+	got := Compare(oldReport, newReport)
+
+	assert.Nil(t, got.BusiestPostcode)
+	assert.Equal(t, []RecipeChange{}, got.Recipes)
+	assert.Equal(t, 5, got.Changes)
+}
+
+func TestCompare_UniqueRecipeCount(t *testing.T) {
+	oldReport, _ := mockReports()
+	newReport, _ := mockReports()
+	newReport.SetUniqueRecipeCount(4)
+
+	got := Compare(oldReport, newReport)
+
+	assert.Equal(t, &CountChange{Old: 3, New: 4, Delta: 1}, got.UniqueRecipeCount)
+	assert.Equal(t, 1, got.Changes)
+}
+
+func TestDiff_WriteText(t *testing.T) {
+	oldReport, newReport := mockReports()
+	newReport.SetUniqueRecipeCount(4)
+
+	buf := bytes.NewBuffer([]byte{})
+	assert.Nil(t, Compare(oldReport, newReport).WriteText(buf))
+
+	want := `unique recipe count: 3 -> 4 (+1)
+count per recipe:
+  + Apple  0 -> 2 (+2)
+  - Pear   1 -> 0 (-1)
+  ~ Steak  4 -> 7 (+3)
+busiest postcode: 10120 (3) -> 10245 (5)
+count per postcode and time:
+  ~ 10120 10AM - 3PM         4 -> 6 (+2)
+  + 10120 10AM - 3PM Monday  0 -> 1 (+1)
+  - 10245 1PM - 6PM          2 -> 0 (-2)
+match by name:
+  + Apple
+  - Pear
+10 changes
+`
+	assert.Equal(t, want, buf.String())
+
+	buf.Reset()
+	assert.Nil(t, Compare(oldReport, oldReport).WriteText(buf))
+	assert.Equal(t, "no changes\n", buf.String())
+}
@@ -0,0 +1,73 @@
+package dlq
+
+import (
+	"bufio"
+	"os"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog/log"
+)
+
+// ChanSize is the buffer size of the dlq channel, so that workers are not blocked on every rejected record
+const ChanSize = 1024
+
+// FileSink writes the records rejected by the processor to a file as newline delimited JSON, one record per line
+type FileSink struct {
+	file    *os.File
+	writer  *bufio.Writer
+	written int
+	err     error
+	done    chan struct{}
+}
+
+// NewFileSink creates (or truncates) the file in path that rejected records are written to
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{
+		file:   f,
+		writer: bufio.NewWriter(f),
+		done:   make(chan struct{}),
+	}, nil
+}
+
+// Listen consumes the dlq channel in the background until it is closed. the channel keeps being drained after a
+// write error so that the processor never blocks on it, the error is returned by Close
+func (s *FileSink) Listen(dlq <-chan *model.RejectedRecipe) {
+	go func() {
+		defer close(s.done)
+
+		enc := json.NewEncoder(s.writer)
+		for rejected := range dlq {
+			if s.err != nil {
+				continue
+			}
+			if s.err = enc.Encode(rejected); s.err == nil {
+				s.written++
+			}
+		}
+		log.Debug().Msgf("dlq channel is closed. %d rejected records written to %s", s.written, s.file.Name())
+	}()
+}
+
+// Close waits for the dlq channel passed to Listen to be closed and drained, then flushes and closes the file
+func (s *FileSink) Close() error {
+	<-s.done
+
+	if err := s.writer.Flush(); err != nil && s.err == nil {
+		s.err = err
+	}
+	if err := s.file.Close(); err != nil && s.err == nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// Written returns the number of records written to the file, it must be called after Close
+func (s *FileSink) Written() int {
+	return s.written
+}
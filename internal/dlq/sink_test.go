@@ -0,0 +1,54 @@
+package dlq
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dlq.ndjson")
+	sink, err := NewFileSink(path)
+	assert.Nil(t, err)
+
+	dlqChan := make(chan *model.RejectedRecipe, ChanSize)
+	sink.Listen(dlqChan)
+
+	dlqChan <- &model.RejectedRecipe{
+		Source: "a.json",
+		Index:  3,
+		Recipe: &model.Recipe{Recipe: "Steak", Delivery: "Thursday 3PM - 4PM"},
+		Reason: "one of required fields [postcode, delivery, recipe] is missing or blank",
+		Code:   model.RejectionMissingField,
+	}
+	dlqChan <- &model.RejectedRecipe{
+		Source: "b.json",
+		Index:  0,
+		Line:   1,
+		Raw:    `{"recipe": "Ste`,
+		Reason: "unexpected end of JSON input",
+		Code:   model.RejectionMalformedRecord,
+	}
+	close(dlqChan)
+
+	assert.Nil(t, sink.Close())
+	assert.Equal(t, 2, sink.Written())
+
+	got, err := os.ReadFile(path)
+	assert.Nil(t, err)
+
+	want := `{"source":"a.json","index":3,"record":{"recipe":"Steak","postcode":"","delivery":"Thursday 3PM - 4PM"},` +
+		`"reason":"one of required fields [postcode, delivery, recipe] is missing or blank","code":"missing_field"}
+{"source":"b.json","index":0,"line":1,"raw":"{\"recipe\": \"Ste","reason":"unexpected end of JSON input",` +
+		`"code":"malformed_record"}
+`
+	assert.Equal(t, want, string(got))
+}
+
+func TestNewFileSink_invalidPath(t *testing.T) {
+	_, err := NewFileSink(filepath.Join(t.TempDir(), "missing", "dlq.ndjson"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,173 @@
+package input
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultMemberPattern selects the archive members that are processed when no pattern is given
+	DefaultMemberPattern = "*.json"
+
+	// StdinPath is the path that stands for the standard input, which is named StdinName in errors
+	StdinPath = "-"
+	StdinName = "<stdin>"
+)
+
+type compression int
+
+const (
+	compressionNone compression = iota
+	compressionGzip
+	compressionZstd
+	compressionTar
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+	// tarMagic is found at tarMagicOffset of the first header block of POSIX/GNU tar archives
+	tarMagic       = []byte("ustar")
+	tarMagicOffset = 257
+)
+
+// FeedFunc is called for every recipe data stream found in the input. name identifies the stream, for archive
+// members it is the archive path followed by the member name (e.g. fixtures.tar.gz:fixtures.json)
+type FeedFunc func(name string, data io.Reader) error
+
+// Walk opens the file in filePath (or the standard input when filePath is StdinPath) and calls feed for every recipe
+// data stream found in it. gzip and zstd compressed files are decompressed on the fly and tar archives are unpacked,
+// feeding every member that matches memberPattern. the compression is detected from the magic bytes of the content,
+// the file extension is not taken into account. reading stops with the error of ctx once it is done
+func Walk(ctx context.Context, filePath, memberPattern string, feed FeedFunc) error {
+	if memberPattern == "" {
+		memberPattern = DefaultMemberPattern
+	}
+	if _, err := path.Match(memberPattern, ""); err != nil {
+		return fmt.Errorf("invalid archive member pattern: %s: %w", memberPattern, err)
+	}
+
+	if filePath == StdinPath {
+		log.Debug().Msg("reading from stdin")
+		return walk(StdinName, NewContextReader(ctx, os.Stdin), memberPattern, feed)
+	}
+
+	log.Debug().Msgf("opening file in path: %s", filePath)
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	return walk(filePath, NewContextReader(ctx, f), memberPattern, feed)
+}
+
+// walk peels off the compression layers of data one at a time until the plain data stream is reached
+func walk(name string, data io.Reader, memberPattern string, feed FeedFunc) error {
+	reader := bufio.NewReader(data)
+
+	switch detectCompression(reader) {
+	case compressionGzip:
+		log.Debug().Msgf("decompressing gzip input: %s", name)
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed opening gzip input %s: %w", name, err)
+		}
+		defer func() { _ = gz.Close() }()
+		return walk(name, gz, memberPattern, feed)
+
+	case compressionZstd:
+		log.Debug().Msgf("decompressing zstd input: %s", name)
+		zr, err := zstd.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed opening zstd input %s: %w", name, err)
+		}
+		defer zr.Close()
+		return walk(name, zr, memberPattern, feed)
+
+	case compressionTar:
+		return walkTar(name, reader, memberPattern, feed)
+
+	default:
+		return feed(name, reader)
+	}
+}
+
+// walkTar feeds every regular member of the tar archive matching memberPattern. hidden members (e.g. the ._ resource
+// forks macOS adds to archives) are skipped
+func walkTar(name string, data io.Reader, memberPattern string, feed FeedFunc) error {
+	tr := tar.NewReader(data)
+
+	var matched int
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed reading tar archive %s: %w", name, err)
+		}
+
+		if header.Typeflag != tar.TypeReg || !matchMember(memberPattern, header.Name) {
+			log.Debug().Msgf("skipping archive member: %s", header.Name)
+			continue
+		}
+
+		matched++
+		if err := walk(name+":"+header.Name, tr, memberPattern, feed); err != nil {
+			return err
+		}
+	}
+
+	if matched == 0 {
+		return fmt.Errorf("no member of archive %s matches pattern: %s", name, memberPattern)
+	}
+	return nil
+}
+
+// matchMember matches the pattern against both the full member path and its base name
+func matchMember(pattern, member string) bool {
+	base := path.Base(member)
+	if strings.HasPrefix(base, ".") {
+		return false
+	}
+
+	fullMatch, _ := path.Match(pattern, member)
+	baseMatch, _ := path.Match(pattern, base)
+	return fullMatch || baseMatch
+}
+
+// detectCompression peeks at the magic bytes of data without consuming them. data starting like JSON is not waited on
+// until the tar header could be peeked, so that a slow stream (e.g. stdin) is decoded as soon as its first record
+// arrives
+func detectCompression(data *bufio.Reader) compression {
+	header, _ := data.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return compressionGzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return compressionZstd
+	case len(header) > 0 && bytes.ContainsAny(header[:1], " \t\r\n[{"):
+		return compressionNone
+	}
+
+	header, _ = data.Peek(tarMagicOffset + len(tarMagic))
+	if len(header) == tarMagicOffset+len(tarMagic) && bytes.Equal(header[tarMagicOffset:], tarMagic) {
+		return compressionTar
+	}
+	return compressionNone
+}
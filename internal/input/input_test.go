@@ -0,0 +1,169 @@
+package input
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+const data = `[{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}]`
+
+func gzipped(t *testing.T, bs []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	_, err := gz.Write(bs)
+	assert.Nil(t, err)
+	assert.Nil(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, bs []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	zw, err := zstd.NewWriter(buf)
+	assert.Nil(t, err)
+	_, err = zw.Write(bs)
+	assert.Nil(t, err)
+	assert.Nil(t, zw.Close())
+	return buf.Bytes()
+}
+
+func tarred(t *testing.T, members map[string]string, order ...string) []byte {
+	buf := bytes.NewBuffer(nil)
+	tw := tar.NewWriter(buf)
+	for _, name := range order {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(members[name])), Typeflag: tar.TypeReg})
+		assert.Nil(t, err)
+		_, err = tw.Write([]byte(members[name]))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestWalk(t *testing.T) {
+	members := map[string]string{
+		"fixtures/a.json":   data,
+		"fixtures/b.json":   data,
+		"fixtures/._a.json": "\x00\x05\x16\x07",
+		"README.md":         "# fixtures",
+	}
+	archive := tarred(t, members, "fixtures/a.json", "fixtures/._a.json", "README.md", "fixtures/b.json")
+
+	tcs := []struct {
+		name          string
+		content       []byte
+		memberPattern string
+		want          []string
+		wantErr       bool
+	}{
+		{
+			name:    "plain file",
+			content: []byte(data),
+			want:    []string{"input"},
+		},
+		{
+			name:    "gzip file",
+			content: gzipped(t, []byte(data)),
+			want:    []string{"input"},
+		},
+		{
+			name:    "zstd file",
+			content: zstded(t, []byte(data)),
+			want:    []string{"input"},
+		},
+		{
+			name:    "tar.gz archive processes every json member",
+			content: gzipped(t, archive),
+			want:    []string{"input:fixtures/a.json", "input:fixtures/b.json"},
+		},
+		{
+			name:          "tar archive with member selector",
+			content:       archive,
+			memberPattern: "fixtures/b.*",
+			want:          []string{"input:fixtures/b.json"},
+		},
+		{
+			name:          "tar archive without matching member",
+			content:       archive,
+			memberPattern: "*.csv",
+			wantErr:       true,
+		},
+		{
+			name:          "invalid member pattern",
+			content:       archive,
+			memberPattern: "[",
+			wantErr:       true,
+		},
+		{
+			name:    "corrupt gzip file",
+			content: gzipped(t, []byte(data))[:12],
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filePath := filepath.Join(dir, "input")
+			assert.Nil(t, os.WriteFile(filePath, tc.content, 0644))
+
+			var got []string
+			err := Walk(context.Background(), filePath, tc.memberPattern, func(name string, r io.Reader) error {
+				bs, err := io.ReadAll(r)
+				if err != nil {
+					return err
+				}
+				assert.Equal(t, data, string(bs))
+				got = append(got, strings.TrimPrefix(name, dir+"/"))
+				return nil
+			})
+
+			if tc.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWalk_missingFile(t *testing.T) {
+	err := Walk(context.Background(), filepath.Join(t.TempDir(), "missing.json"), "", func(string, io.Reader) error {
+		return nil
+	})
+	assert.NotNil(t, err)
+}
+
+func TestWalk_stdin(t *testing.T) {
+	stdin, err := os.CreateTemp(t.TempDir(), "stdin")
+	assert.Nil(t, err)
+	_, err = stdin.Write(gzipped(t, []byte(data)))
+	assert.Nil(t, err)
+	_, err = stdin.Seek(0, io.SeekStart)
+	assert.Nil(t, err)
+
+	origStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = origStdin }()
+
+	var got []string
+	err = Walk(context.Background(), StdinPath, "", func(name string, r io.Reader) error {
+		bs, err := io.ReadAll(r)
+		assert.Equal(t, data, string(bs))
+		got = append(got, name)
+		return err
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{StdinName}, got)
+}
@@ -0,0 +1,49 @@
+package input
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader reads from its reader until ctx is done. a read blocked on the reader (e.g. on stdin, a slow pipe or a
+// network mount) is abandoned once ctx is done, so that processing stops on time instead of once the read returns
+type contextReader struct {
+	ctx     context.Context
+	r       io.Reader
+	buf     []byte
+	results chan readResult
+}
+
+type readResult struct {
+	n   int
+	err error
+}
+
+// NewContextReader returns a reader reading from r that fails with the error of ctx as soon as ctx is done, even
+// while a read is blocked on r
+func NewContextReader(ctx context.Context, r io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, r: r, results: make(chan readResult, 1)}
+}
+
+// Read reads into a buffer of its own, an abandoned read may still complete once the caller moved on
+func (cr *contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if cap(cr.buf) < len(p) {
+		cr.buf = make([]byte, len(p))
+	}
+	buf := cr.buf[:len(p)]
+	go func() {
+		n, err := cr.r.Read(buf)
+		cr.results <- readResult{n: n, err: err}
+	}()
+
+	select {
+	case res := <-cr.results:
+		return copy(p, buf[:res.n]), res.err
+	case <-cr.ctx.Done():
+		return 0, cr.ctx.Err()
+	}
+}
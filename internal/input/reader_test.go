@@ -0,0 +1,25 @@
+package input
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewContextReader(t *testing.T) {
+	got, err := io.ReadAll(NewContextReader(context.Background(), strings.NewReader(data)))
+	assert.Nil(t, err)
+	assert.Equal(t, data, string(got))
+
+	// nothing is ever written to the pipe, the read only returns because the context is done
+	blocked, _ := io.Pipe()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = NewContextReader(ctx, blocked).Read(make([]byte, 10))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,116 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Resolve expands the given paths into the list of files to process. a path can be a file, a directory (every
+// non-hidden regular file directly inside it is used), a glob pattern or StdinPath. every path must resolve to at least
+// one file, duplicates are dropped while the order of the paths is kept
+func Resolve(paths []string) ([]string, error) {
+	var resolved []string
+	seen := make(map[string]bool)
+
+	for _, p := range paths {
+		files, err := resolvePath(p)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no files found matching: %s", p)
+		}
+
+		for _, f := range files {
+			if !seen[f] {
+				seen[f] = true
+				resolved = append(resolved, f)
+			}
+		}
+	}
+
+	return resolved, nil
+}
+
+// resolvePath expands a single path, files found in directories and glob matches are sorted by name
+func resolvePath(p string) ([]string, error) {
+	if p == StdinPath {
+		return []string{p}, nil
+	}
+
+	if strings.ContainsAny(p, "*?[") {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern: %s: %w", p, err)
+		}
+		return regularFiles(matches), nil
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{p}, nil
+	}
+
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			files = append(files, filepath.Join(p, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// regularFiles filters out everything that is not a regular file (directories matched by a glob etc.)
+func regularFiles(paths []string) []string {
+	files := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+// WalkAll walks every file in filePaths concurrently, see Walk. feed may therefore be called concurrently.
+// the first error encountered is returned once all the walks are done
+func WalkAll(ctx context.Context, filePaths []string, memberPattern string, feed FeedFunc) error {
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	// limit the amount of files that are open and decoded at the same time
+	sem := make(chan struct{}, runtime.NumCPU())
+
+	wg.Add(len(filePaths))
+	for _, filePath := range filePaths {
+		go func(filePath string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := Walk(ctx, filePath, memberPattern, feed); err != nil {
+				errOnce.Do(func() { firstErr = err })
+			}
+		}(filePath)
+	}
+	wg.Wait()
+
+	return firstErr
+}
@@ -0,0 +1,112 @@
+package input
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"2023-01-01.json", "2023-01-02.json", "2023-02-01.json", ".hidden.json"} {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+	}
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "2023-03-01.json"), 0755))
+
+	tcs := []struct {
+		name    string
+		paths   []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "single file",
+			paths: []string{filepath.Join(dir, "2023-01-02.json")},
+			want:  []string{"2023-01-02.json"},
+		},
+		{
+			name:  "glob pattern skips directories",
+			paths: []string{filepath.Join(dir, "2023-0*.json")},
+			want:  []string{"2023-01-01.json", "2023-01-02.json", "2023-02-01.json"},
+		},
+		{
+			name:  "directory skips hidden files",
+			paths: []string{dir},
+			want:  []string{"2023-01-01.json", "2023-01-02.json", "2023-02-01.json"},
+		},
+		{
+			name:  "repeated paths are deduplicated",
+			paths: []string{filepath.Join(dir, "2023-02-01.json"), filepath.Join(dir, "2023-01-*.json")},
+			want:  []string{"2023-02-01.json", "2023-01-01.json", "2023-01-02.json"},
+		},
+		{
+			name:  "stdin is kept as is",
+			paths: []string{StdinPath, filepath.Join(dir, "2023-02-01.json")},
+			want:  []string{StdinPath, "2023-02-01.json"},
+		},
+		{
+			name:    "glob without matches",
+			paths:   []string{filepath.Join(dir, "2024-*.json")},
+			wantErr: true,
+		},
+		{
+			name:    "missing file",
+			paths:   []string{filepath.Join(dir, "missing.json")},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Resolve(tc.paths)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			gotNames := make([]string, 0, len(got))
+			for _, p := range got {
+				gotNames = append(gotNames, filepath.Base(p))
+			}
+			assert.Equal(t, tc.want, gotNames)
+		})
+	}
+}
+
+func TestWalkAll(t *testing.T) {
+	dir := t.TempDir()
+	var filePaths []string
+	for _, name := range []string{"a.json", "b.json", "c.json"} {
+		filePath := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(filePath, []byte(data), 0644))
+		filePaths = append(filePaths, filePath)
+	}
+
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	err := WalkAll(context.Background(), filePaths, "", func(name string, r io.Reader) error {
+		_, err := io.ReadAll(r)
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, filepath.Base(name))
+		return err
+	})
+	assert.Nil(t, err)
+
+	sort.Strings(got)
+	assert.Equal(t, []string{"a.json", "b.json", "c.json"}, got)
+
+	err = WalkAll(context.Background(), append(filePaths, filepath.Join(dir, "missing.json")), "", func(string, io.Reader) error {
+		return nil
+	})
+	assert.NotNil(t, err)
+}
@@ -1,13 +1,36 @@
 package model
 
 import (
+	"fmt"
+	"strings"
 	"time"
 )
 
+// timeLayouts are the layouts delivery times are parsed with: 12-hour clock with optional minutes (3PM, 9:30AM) and
+// 24-hour clock (14:00)
+var timeLayouts = []string{"3PM", "3:04PM", "15:04"}
+
+// TimeFormat controls how delivery times are rendered in the report
+type TimeFormat string
+
 const (
-	timeLayout = "3PM"
+	// TimeFormatRaw renders delivery times the way they were passed
+	TimeFormatRaw TimeFormat = "raw"
+	TimeFormat12h TimeFormat = "12h"
+	TimeFormat24h TimeFormat = "24h"
 )
 
+// ParseTimeFormat validates that format is one of the supported time formats
+func ParseTimeFormat(format string) (TimeFormat, error) {
+	switch f := TimeFormat(strings.ToLower(format)); f {
+	case TimeFormatRaw, TimeFormat12h, TimeFormat24h:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unsupported time format %q, must be one of [%s, %s, %s]", format, TimeFormatRaw,
+			TimeFormat12h, TimeFormat24h)
+	}
+}
+
 type DeliveryTime struct {
 	time.Time
 	raw string
@@ -22,14 +45,20 @@ func NewDeliveryTime(input string) (*DeliveryTime, error) {
 	return &d, err
 }
 
-// parse converts a string of time with PM or AM to a time object (e.g. 3PM)
+// parse converts a string of time to a time object, either with PM or AM and optional minutes (e.g. 3PM, 9:30am) or
+// in 24-hour clock (e.g. 14:00)
 func (dt *DeliveryTime) parse(input string) error {
-	t, err := time.Parse(timeLayout, input)
-	if err != nil {
-		return err
+	normalized := strings.ToUpper(input)
+
+	var err error
+	for _, layout := range timeLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, normalized); err == nil {
+			dt.Time = t
+			return nil
+		}
 	}
-	dt.Time = t
-	return nil
+	return fmt.Errorf("invalid time %q, must be in the format 3PM, 9:30AM or 14:00: %w", input, err)
 }
 
 // Raw returns the raw string passed to instantiate DeliveryTime
@@ -51,3 +80,24 @@ func (dt *DeliveryTime) InclusiveBefore(t *DeliveryTime) bool {
 func (dt *DeliveryTime) InclusiveBetween(start, end *DeliveryTime) bool {
 	return dt.InclusiveAfter(start) && dt.InclusiveBefore(end)
 }
+
+// Format renders the time in format, the raw format is the string the time was instantiated with. the 12-hour format
+// leaves out zero minutes (3PM, 9:30AM)
+func (dt *DeliveryTime) Format(format TimeFormat) string {
+	switch format {
+	case TimeFormat12h:
+		if dt.Minute() == 0 {
+			return dt.Time.Format("3PM")
+		}
+		return dt.Time.Format("3:04PM")
+	case TimeFormat24h:
+		return dt.Time.Format("15:04")
+	default:
+		return dt.raw
+	}
+}
+
+// MinuteOfDay returns the number of minutes since midnight
+func (dt *DeliveryTime) MinuteOfDay() int {
+	return dt.Hour()*60 + dt.Minute()
+}
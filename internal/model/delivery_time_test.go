@@ -151,3 +151,122 @@ func TestDeliveryTime_InclusiveBetween(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDeliveryTime(t *testing.T) {
+	tcs := []struct {
+		name       string
+		input      string
+		wantHour   int
+		wantMinute int
+		wantErr    bool
+	}{
+		{
+			name:     "12-hour clock",
+			input:    "3PM",
+			wantHour: 15,
+		},
+		{
+			name:       "12-hour clock with minutes",
+			input:      "9:30AM",
+			wantHour:   9,
+			wantMinute: 30,
+		},
+		{
+			name:       "lower case 12-hour clock",
+			input:      "1:15pm",
+			wantHour:   13,
+			wantMinute: 15,
+		},
+		{
+			name:       "24-hour clock",
+			input:      "14:45",
+			wantHour:   14,
+			wantMinute: 45,
+		},
+		{
+			name:     "24-hour clock single digit hour",
+			input:    "9:00",
+			wantHour: 9,
+		},
+		{
+			name:    "hour out of range",
+			input:   "13PM",
+			wantErr: true,
+		},
+		{
+			name:    "24-hour clock out of range",
+			input:   "24:00",
+			wantErr: true,
+		},
+		{
+			name:    "not a time",
+			input:   "noon",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewDeliveryTime(tc.input)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantHour, got.Hour())
+			assert.Equal(t, tc.wantMinute, got.Minute())
+			assert.Equal(t, tc.input, got.Raw())
+		})
+	}
+}
+
+func TestDeliveryTime_Format(t *testing.T) {
+	tcs := []struct {
+		name   string
+		input  string
+		format TimeFormat
+		want   string
+	}{
+		{
+			name:   "raw",
+			input:  "9:30am",
+			format: TimeFormatRaw,
+			want:   "9:30am",
+		},
+		{
+			name:   "12-hour clock",
+			input:  "14:00",
+			format: TimeFormat12h,
+			want:   "2PM",
+		},
+		{
+			name:   "12-hour clock with minutes",
+			input:  "14:05",
+			format: TimeFormat12h,
+			want:   "2:05PM",
+		},
+		{
+			name:   "24-hour clock",
+			input:  "9:30AM",
+			format: TimeFormat24h,
+			want:   "09:30",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dt, err := NewDeliveryTime(tc.input)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, dt.Format(tc.format))
+		})
+	}
+}
+
+func TestParseTimeFormat(t *testing.T) {
+	got, err := ParseTimeFormat("24H")
+	assert.Nil(t, err)
+	assert.Equal(t, TimeFormat24h, got)
+
+	_, err = ParseTimeFormat("iso")
+	assert.NotNil(t, err)
+}
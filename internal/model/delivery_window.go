@@ -0,0 +1,144 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+
+	HoursPerDay  = 24
+	HoursPerWeek = 7 * HoursPerDay
+)
+
+// MatchMode decides when a delivery window matches the window of a query
+type MatchMode string
+
+const (
+	// MatchContained the delivery starts and ends within the query window
+	MatchContained MatchMode = "contained"
+	// MatchOverlaps the delivery shares at least a minute with the query window
+	MatchOverlaps MatchMode = "overlaps"
+	// MatchStartsWithin the delivery starts within the query window
+	MatchStartsWithin MatchMode = "starts-within"
+	// MatchEndsWithin the delivery ends within the query window
+	MatchEndsWithin MatchMode = "ends-within"
+)
+
+// MatchModes lists all the supported match modes
+var MatchModes = []MatchMode{MatchContained, MatchOverlaps, MatchStartsWithin, MatchEndsWithin}
+
+// ParseMatchMode validates that mode is one of the supported match modes
+func ParseMatchMode(mode string) (MatchMode, error) {
+	for _, matchMode := range MatchModes {
+		if MatchMode(strings.ToLower(mode)) == matchMode {
+			return matchMode, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported match mode %q, must be one of %v", mode, MatchModes)
+}
+
+// DeliveryWindow is a delivery timespan anchored at the weekday it starts on. a window whose end is before its start
+// crosses midnight and ends on the next weekday (e.g. Friday 10PM - 2AM ends on Saturday 2AM, Sunday 10PM - 2AM ends
+// on Monday)
+type DeliveryWindow struct {
+	Weekday  time.Weekday
+	From, To *DeliveryTime
+}
+
+// Start returns the minute of the week the window starts at, weeks start on Sunday at midnight
+func (w DeliveryWindow) Start() int {
+	return int(w.Weekday)*minutesPerDay + w.From.MinuteOfDay()
+}
+
+// Duration returns the length of the window in minutes
+func (w DeliveryWindow) Duration() int {
+	return floorMod(w.To.MinuteOfDay()-w.From.MinuteOfDay(), minutesPerDay)
+}
+
+// CrossesMidnight whether the window ends on the weekday after the one it starts on
+func (w DeliveryWindow) CrossesMidnight() bool {
+	return w.To.MinuteOfDay() < w.From.MinuteOfDay()
+}
+
+// Within whether w lies entirely within other, start and end inclusive. windows wrap around the end of the week, so
+// that a Saturday 10PM - 2AM window lies within a Saturday 8PM - 3AM window
+func (w DeliveryWindow) Within(other DeliveryWindow) bool {
+	offset := floorMod(w.Start()-other.Start(), minutesPerWeek)
+	return offset+w.Duration() <= other.Duration()
+}
+
+// StartsWithin whether w starts within other, start and end inclusive
+func (w DeliveryWindow) StartsWithin(other DeliveryWindow) bool {
+	return floorMod(w.Start()-other.Start(), minutesPerWeek) <= other.Duration()
+}
+
+// EndsWithin whether w ends within other, start and end inclusive
+func (w DeliveryWindow) EndsWithin(other DeliveryWindow) bool {
+	return floorMod(w.Start()+w.Duration()-other.Start(), minutesPerWeek) <= other.Duration()
+}
+
+// Overlap returns the number of minutes w and other have in common
+func (w DeliveryWindow) Overlap(other DeliveryWindow) int {
+	// w relative to the start of other, w may start before other in which case it starts a week earlier
+	offset := floorMod(w.Start()-other.Start(), minutesPerWeek)
+	return overlap(offset, offset+w.Duration(), other.Duration()) +
+		overlap(offset-minutesPerWeek, offset-minutesPerWeek+w.Duration(), other.Duration())
+}
+
+// Matches whether w matches other according to mode
+func (w DeliveryWindow) Matches(other DeliveryWindow, mode MatchMode) bool {
+	switch mode {
+	case MatchOverlaps:
+		return w.Overlap(other) > 0
+	case MatchStartsWithin:
+		return w.StartsWithin(other)
+	case MatchEndsWithin:
+		return w.EndsWithin(other)
+	default:
+		return w.Within(other)
+	}
+}
+
+// HoursOfWeek returns the hours of the week (0 being Sunday 12AM - 1AM) the window is active during, an empty window is
+// active during the hour it starts in
+func (w DeliveryWindow) HoursOfWeek() []int {
+	return HoursOfWeek(w.Start(), w.Duration())
+}
+
+// HoursOfWeek returns the hours of the week a window starting at the start minute of the week and lasting duration
+// minutes is active during, wrapping around the end of the week
+func HoursOfWeek(start, duration int) []int {
+	first, last := start/60, (start+duration+59)/60
+	if last == first {
+		last++
+	}
+
+	hours := make([]int, 0, last-first)
+	for hour := first; hour < last; hour++ {
+		hours = append(hours, hour%HoursPerWeek)
+	}
+	return hours
+}
+
+// overlap returns the number of minutes the start - end interval has in common with the 0 - length interval
+func overlap(start, end, length int) int {
+	if start < 0 {
+		start = 0
+	}
+	if end > length {
+		end = length
+	}
+	if end < start {
+		return 0
+	}
+	return end - start
+}
+
+// floorMod returns a modulo m that is never negative
+func floorMod(a, m int) int {
+	return ((a % m) + m) % m
+}
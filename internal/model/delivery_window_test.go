@@ -0,0 +1,241 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockWindow(weekday time.Weekday, from, to string) DeliveryWindow {
+	deliveryFrom, err := NewDeliveryTime(from)
+	if err != nil {
+		panic(err)
+	}
+	deliveryTo, err := NewDeliveryTime(to)
+	if err != nil {
+		panic(err)
+	}
+	return DeliveryWindow{Weekday: weekday, From: deliveryFrom, To: deliveryTo}
+}
+
+func TestDeliveryWindow_Duration(t *testing.T) {
+	tcs := []struct {
+		name                string
+		window              DeliveryWindow
+		want                int
+		wantCrossesMidnight bool
+	}{
+		{
+			name:   "same day",
+			window: mockWindow(time.Friday, "10AM", "3PM"),
+			want:   5 * 60,
+		},
+		{
+			name:                "crosses midnight",
+			window:              mockWindow(time.Friday, "10PM", "2AM"),
+			want:                4 * 60,
+			wantCrossesMidnight: true,
+		},
+		{
+			name:   "ends at midnight",
+			window: mockWindow(time.Friday, "8PM", "12AM"),
+			// 12AM is the start of the day, the window therefore ends on the next day
+			want:                4 * 60,
+			wantCrossesMidnight: true,
+		},
+		{
+			name:   "empty window",
+			window: mockWindow(time.Friday, "3PM", "3PM"),
+			want:   0,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.window.Duration())
+			assert.Equal(t, tc.wantCrossesMidnight, tc.window.CrossesMidnight())
+		})
+	}
+}
+
+func TestDeliveryWindow_Within(t *testing.T) {
+	tcs := []struct {
+		name   string
+		window DeliveryWindow
+		other  DeliveryWindow
+		want   bool
+	}{
+		{
+			name:   "same day within",
+			window: mockWindow(time.Thursday, "11AM", "1PM"),
+			other:  mockWindow(time.Thursday, "10AM", "3PM"),
+			want:   true,
+		},
+		{
+			name:   "equal windows",
+			window: mockWindow(time.Thursday, "10AM", "3PM"),
+			other:  mockWindow(time.Thursday, "10AM", "3PM"),
+			want:   true,
+		},
+		{
+			name:   "ends after",
+			window: mockWindow(time.Thursday, "3PM", "4PM"),
+			other:  mockWindow(time.Thursday, "10AM", "3PM"),
+			want:   false,
+		},
+		{
+			name:   "other weekday",
+			window: mockWindow(time.Wednesday, "11AM", "1PM"),
+			other:  mockWindow(time.Thursday, "10AM", "3PM"),
+			want:   false,
+		},
+		{
+			name:   "overnight within overnight",
+			window: mockWindow(time.Friday, "10PM", "2AM"),
+			other:  mockWindow(time.Friday, "8PM", "2AM"),
+			want:   true,
+		},
+		{
+			name:   "after midnight within overnight of the previous day",
+			window: mockWindow(time.Saturday, "12AM", "1AM"),
+			other:  mockWindow(time.Friday, "8PM", "2AM"),
+			want:   true,
+		},
+		{
+			name:   "overnight not within same day",
+			window: mockWindow(time.Friday, "10PM", "2AM"),
+			other:  mockWindow(time.Friday, "1PM", "11PM"),
+			want:   false,
+		},
+		{
+			name:   "overnight across the end of the week",
+			window: mockWindow(time.Saturday, "11PM", "1AM"),
+			other:  mockWindow(time.Saturday, "10PM", "3AM"),
+			want:   true,
+		},
+		{
+			name:   "start of the week within overnight of the end of the week",
+			window: mockWindow(time.Sunday, "12AM", "2AM"),
+			other:  mockWindow(time.Saturday, "10PM", "3AM"),
+			want:   true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.window.Within(tc.other))
+		})
+	}
+}
+
+func TestDeliveryWindow_Matches(t *testing.T) {
+	query := mockWindow(time.Friday, "8PM", "2AM")
+
+	tcs := []struct {
+		name        string
+		window      DeliveryWindow
+		want        map[MatchMode]bool
+		wantOverlap int
+	}{
+		{
+			name:   "contained",
+			window: mockWindow(time.Friday, "10PM", "1AM"),
+			want: map[MatchMode]bool{
+				MatchContained: true, MatchOverlaps: true, MatchStartsWithin: true, MatchEndsWithin: true,
+			},
+			wantOverlap: 3 * 60,
+		},
+		{
+			name:        "starts before",
+			window:      mockWindow(time.Friday, "7PM", "9:30PM"),
+			want:        map[MatchMode]bool{MatchOverlaps: true, MatchEndsWithin: true},
+			wantOverlap: 90,
+		},
+		{
+			name:        "ends after",
+			window:      mockWindow(time.Saturday, "1AM", "4AM"),
+			want:        map[MatchMode]bool{MatchOverlaps: true, MatchStartsWithin: true},
+			wantOverlap: 60,
+		},
+		{
+			name:        "covers",
+			window:      mockWindow(time.Friday, "6PM", "3AM"),
+			want:        map[MatchMode]bool{MatchOverlaps: true},
+			wantOverlap: 6 * 60,
+		},
+		{
+			name:        "touches",
+			window:      mockWindow(time.Friday, "6PM", "8PM"),
+			want:        map[MatchMode]bool{MatchEndsWithin: true},
+			wantOverlap: 0,
+		},
+		{
+			name:        "other day",
+			window:      mockWindow(time.Thursday, "10PM", "1AM"),
+			want:        map[MatchMode]bool{},
+			wantOverlap: 0,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, mode := range MatchModes {
+				assert.Equal(t, tc.want[mode], tc.window.Matches(query, mode), mode)
+			}
+			assert.Equal(t, tc.wantOverlap, tc.window.Overlap(query))
+		})
+	}
+}
+
+func TestDeliveryWindow_OverlapAcrossEndOfWeek(t *testing.T) {
+	// Saturday 10PM - 3AM ends on Sunday, which is the start of the week
+	query := mockWindow(time.Saturday, "10PM", "3AM")
+
+	assert.Equal(t, 2*60, mockWindow(time.Sunday, "1AM", "4AM").Overlap(query))
+	assert.Equal(t, 60, mockWindow(time.Saturday, "9PM", "11PM").Overlap(query))
+}
+
+func TestDeliveryWindow_HoursOfWeek(t *testing.T) {
+	tcs := []struct {
+		name   string
+		window DeliveryWindow
+		want   []int
+	}{
+		{
+			name:   "whole hours",
+			window: mockWindow(time.Monday, "10AM", "1PM"),
+			want:   []int{34, 35, 36},
+		},
+		{
+			name:   "partial hours",
+			window: mockWindow(time.Monday, "10:30AM", "11:15AM"),
+			want:   []int{34, 35},
+		},
+		{
+			name:   "empty window is active during the hour it starts in",
+			window: mockWindow(time.Monday, "10:30AM", "10:30AM"),
+			want:   []int{34},
+		},
+		{
+			name:   "crosses the end of the week",
+			window: mockWindow(time.Saturday, "11PM", "1AM"),
+			want:   []int{167, 0},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.window.HoursOfWeek())
+		})
+	}
+}
+
+func TestParseMatchMode(t *testing.T) {
+	got, err := ParseMatchMode("Starts-Within")
+	assert.Nil(t, err)
+	assert.Equal(t, MatchStartsWithin, got)
+
+	_, err = ParseMatchMode("around")
+	assert.NotNil(t, err)
+}
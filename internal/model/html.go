@@ -0,0 +1,122 @@
+package model
+
+import (
+	"io"
+	"sort"
+)
+
+// htmlTemplate is the bundled template the html format is rendered with, it embeds its styles and scripts so that the
+// page can be viewed offline
+const htmlTemplate = "report.html"
+
+const (
+	// chartBars is the number of bars of a chart, the rest is left out
+	chartBars = 10
+
+	// chartLabelWidth, chartBarWidth, chartValueWidth and chartRowHeight lay out the charts in pixels
+	chartLabelWidth = 220
+	chartBarWidth   = 420
+	chartValueWidth = 60
+	chartRowHeight  = 24
+
+	// chartLabelLength is the number of characters of a label that fit chartLabelWidth
+	chartLabelLength = 30
+)
+
+// chart is a horizontal bar chart, the longest bar represents the highest value. bars start at LabelWidth, the
+// labels are right aligned to it
+type chart struct {
+	Bars       []chartBar
+	LabelWidth int
+	Width      int
+	Height     int
+}
+
+// chartBar is a single bar of a chart, Y is the top of its row. Label is shortened to fit the chart, Title is the full
+// label
+type chartBar struct {
+	Label string
+	Title string
+	Value int
+	Width int
+	Y     int
+}
+
+// newChart lays out a bar per value, at most chartBars of them
+func newChart(labels []string, values []int) chart {
+	c := chart{LabelWidth: chartLabelWidth, Width: chartLabelWidth + chartBarWidth + chartValueWidth}
+	highest := 0
+	for _, value := range values {
+		if value > highest {
+			highest = value
+		}
+	}
+	for i := 0; i < len(values) && i < chartBars; i++ {
+		bar := chartBar{Label: labels[i], Title: labels[i], Value: values[i], Y: i * chartRowHeight}
+		if label := []rune(labels[i]); len(label) > chartLabelLength {
+			bar.Label = string(label[:chartLabelLength-1]) + "…"
+		}
+		if highest > 0 {
+			bar.Width = values[i] * chartBarWidth / highest
+		}
+		c.Bars = append(c.Bars, bar)
+	}
+	c.Height = len(c.Bars) * chartRowHeight
+	return c
+}
+
+// htmlRenderer renders the report as a single HTML page with a sortable table of the recipe counts, bar charts of the
+// top recipes and postcodes and the postcode counts. it has no external assets
+type htmlRenderer struct{}
+
+func (htmlRenderer) Render(report *ReportModel, out io.Writer) error {
+	renderer, err := NewTemplateRenderer(htmlTemplate)
+	if err != nil {
+		return err
+	}
+	return renderer.Render(report, out)
+}
+
+// RecipeChart charts the most delivered recipes of the report
+func (d TemplateData) RecipeChart() chart {
+	return topRecipesChart(d.CountPerRecipe)
+}
+
+// PostcodeChart charts the top postcodes of the report, or its busiest postcode when it does not rank them
+func (d TemplateData) PostcodeChart() chart {
+	return topPostcodesChart(d.ReportModel)
+}
+
+// topRecipesChart charts the most delivered recipes, ties are ordered by recipe name
+func topRecipesChart(recipeCounts RecipeCounts) chart {
+	sorted := append(RecipeCounts(nil), recipeCounts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].RecipeCount > sorted[j].RecipeCount
+	})
+
+	labels := make([]string, 0, len(sorted))
+	values := make([]int, 0, len(sorted))
+	for _, recipeCount := range sorted {
+		labels = append(labels, recipeCount.Recipe)
+		values = append(values, recipeCount.RecipeCount)
+	}
+	return newChart(labels, values)
+}
+
+// topPostcodesChart charts the top postcodes, or the busiest postcode when the report does not rank them
+func topPostcodesChart(report *ReportModel) chart {
+	if len(report.TopPostcodes) == 0 {
+		if report.BusiestPostcode.Postcode == "" {
+			return chart{}
+		}
+		return newChart([]string{report.BusiestPostcode.Postcode}, []int{report.BusiestPostcode.DeliveryCount})
+	}
+
+	labels := make([]string, 0, len(report.TopPostcodes))
+	values := make([]int, 0, len(report.TopPostcodes))
+	for _, postcode := range report.TopPostcodes {
+		labels = append(labels, postcode.Postcode)
+		values = append(values, postcode.DeliveryCount)
+	}
+	return newChart(labels, values)
+}
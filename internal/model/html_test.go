@@ -0,0 +1,85 @@
+package model
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewChart(t *testing.T) {
+	labels := make([]string, 0, chartBars+2)
+	values := make([]int, 0, chartBars+2)
+	for i := 0; i < chartBars+2; i++ {
+		labels = append(labels, strings.Repeat("x", i+25))
+		values = append(values, 100-i*10)
+	}
+
+	got := newChart(labels, values)
+
+	assert.Len(t, got.Bars, chartBars)
+	assert.Equal(t, chartBars*chartRowHeight, got.Height)
+	assert.Equal(t, chartBar{Label: labels[0], Title: labels[0], Value: 100, Width: chartBarWidth}, got.Bars[0])
+	assert.Equal(t, chartBar{
+		Label: strings.Repeat("x", chartLabelLength-1) + "…",
+		Title: labels[9],
+		Value: 10,
+		Width: chartBarWidth / 10,
+		Y:     9 * chartRowHeight,
+	}, got.Bars[9])
+
+	assert.Equal(t, []chartBar{{Label: "Tacos", Title: "Tacos"}}, newChart([]string{"Tacos"}, []int{0}).Bars)
+}
+
+func TestHTMLRenderer_Render(t *testing.T) {
+	report := mockRenderReport()
+	report.SetCountPerRecipe(RecipeCounts{
+		{Recipe: "Honey, Mustard", RecipeCount: 3},
+		{Recipe: "Mac & <Cheese>", RecipeCount: 4},
+		{Recipe: "Steak", RecipeCount: 1},
+	})
+	report.SetTopPostcodes([]RankedPostcodeCount{
+		{Rank: 1, Postcode: "10245", DeliveryCount: 3, Share: 60},
+		{Rank: 2, Postcode: "10120", DeliveryCount: 2, Share: 40},
+	})
+	report.SetCountPerPostcodeAndTime([]PostcodeTimeCount{
+		{Postcode: "10120", From: "10AM", To: "3PM", Days: []string{"Monday"}, DeliveryCount: 2},
+	})
+
+	buf := bytes.NewBuffer([]byte{})
+	assert.Nil(t, NewRenderer(FormatHTML).Render(report, buf))
+	got := buf.String()
+
+	// the top recipes are charted by their count, the most delivered first
+	recipeChart := got[strings.Index(got, "Top recipes"):strings.Index(got, "Top postcodes")]
+	assert.Equal(t, 3, strings.Count(recipeChart, "<rect"))
+	assert.Less(t, strings.Index(recipeChart, "Mac &amp; &lt;Cheese&gt;"), strings.Index(recipeChart, "Honey, Mustard"))
+	assert.Less(t, strings.Index(recipeChart, "Honey, Mustard"), strings.Index(recipeChart, "Steak"))
+
+	postcodeChart := got[strings.Index(got, "Top postcodes"):strings.Index(got, "Deliveries per recipe")]
+	assert.Equal(t, 2, strings.Count(postcodeChart, "<rect"))
+
+	assert.Contains(t, got, `<table class="sortable">`)
+	assert.Contains(t, got, "<tr><td>Mac &amp; &lt;Cheese&gt;</td><td class=\"number\">4</td>")
+	assert.Contains(t, got, "<tr><td>10120</td><td>10AM</td><td>3PM</td><td>Monday</td><td class=\"number\">2</td>")
+	assert.Contains(t, got, "<td>missing_field</td>")
+
+	// the page is viewed offline, it must not load any asset
+	for _, external := range []string{"http://", "https://", " src=", "<link"} {
+		assert.NotContains(t, got, external)
+	}
+}
+
+func TestHTMLRenderer_RenderTemplate(t *testing.T) {
+	want := bytes.NewBuffer([]byte{})
+	assert.Nil(t, NewRenderer(FormatHTML).Render(mockRenderReport(), want))
+
+	renderer, err := NewTemplateRenderer(htmlTemplate)
+	assert.Nil(t, err)
+	got := bytes.NewBuffer([]byte{})
+	assert.Nil(t, renderer.Render(mockRenderReport(), got))
+
+	// the html format is the bundled template
+	assert.Equal(t, want.String(), got.String())
+}
@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type (
 	Recipes []*Recipe
 
@@ -9,5 +11,30 @@ type (
 		Delivery string        `json:"delivery"`
 		From     *DeliveryTime `json:"-"`
 		To       *DeliveryTime `json:"-"`
+		Weekday  time.Weekday  `json:"-"`
+
+		// Source is the name of the input the recipe was read from
+		Source string `json:"-"`
+		// Index is the zero based position of the recipe in its input
+		Index int `json:"-"`
+		// Line is the line the recipe was read from in newline delimited input, 0 otherwise
+		Line int `json:"-"`
+	}
+
+	// RejectedRecipe is an input record that failed decoding or validation together with the reason it was rejected.
+	// Raw holds the undecodable input when the record could not be decoded into a Recipe
+	RejectedRecipe struct {
+		Source string          `json:"source,omitempty"`
+		Index  int             `json:"index"`
+		Line   int             `json:"line,omitempty"`
+		Recipe *Recipe         `json:"record,omitempty"`
+		Raw    string          `json:"raw,omitempty"`
+		Reason string          `json:"reason"`
+		Code   RejectionReason `json:"code"`
 	}
 )
+
+// DeliveryWindow returns the parsed delivery timespan of the recipe anchored at its weekday
+func (r *Recipe) DeliveryWindow() DeliveryWindow {
+	return DeliveryWindow{Weekday: r.Weekday, From: r.From, To: r.To}
+}
@@ -0,0 +1,234 @@
+package model
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format of the report
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatNDJSON   Format = "ndjson"
+	FormatYAML     Format = "yaml"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "markdown"
+	FormatTable    Format = "table"
+	FormatHTML     Format = "html"
+)
+
+// Formats lists all the supported output formats
+var Formats = []Format{FormatJSON, FormatNDJSON, FormatYAML, FormatCSV, FormatMarkdown, FormatTable, FormatHTML}
+
+// ParseFormat validates that format is one of the supported output formats
+func ParseFormat(format string) (Format, error) {
+	for _, f := range Formats {
+		if Format(strings.ToLower(format)) == f {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported output format %q, must be one of %v", format, Formats)
+}
+
+// Renderer writes a report in a single output format
+type Renderer interface {
+	Render(report *ReportModel, out io.Writer) error
+}
+
+// NewRenderer returns the Renderer of format. json renders the report as Dumps does, ndjson renders every section as a
+// JSON object of its own line and yaml keeps the sections in the same order. csv, markdown and table cannot hold nested
+// data and render every section as its own table, see tabulate. html renders a self-contained page with charts
+func NewRenderer(format Format) Renderer {
+	switch format {
+	case FormatNDJSON:
+		return ndjsonRenderer{}
+	case FormatYAML:
+		return yamlRenderer{}
+	case FormatCSV:
+		return csvRenderer{}
+	case FormatMarkdown:
+		return markdownRenderer{}
+	case FormatTable:
+		return tableRenderer{}
+	case FormatHTML:
+		return htmlRenderer{}
+	default:
+		return jsonRenderer{}
+	}
+}
+
+type (
+	jsonRenderer     struct{}
+	ndjsonRenderer   struct{}
+	yamlRenderer     struct{}
+	csvRenderer      struct{}
+	markdownRenderer struct{}
+	tableRenderer    struct{}
+)
+
+func (jsonRenderer) Render(report *ReportModel, out io.Writer) error {
+	return report.Dumps(out)
+}
+
+func (ndjsonRenderer) Render(report *ReportModel, out io.Writer) error {
+	for _, section := range report.sections() {
+		line, err := json.Marshal(map[string]interface{}{section.name: section.value})
+		if err != nil {
+			return fmt.Errorf("failed encoding report section %s: %w", section.name, err)
+		}
+		if _, err := fmt.Fprintln(out, string(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (yamlRenderer) Render(report *ReportModel, out io.Writer) error {
+	tree, err := decodeReport(report)
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(tree)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// csvRenderer renders every table as a row holding the name of the table, followed by the header and the rows of the
+// table. tables are separated by an empty line
+func (csvRenderer) Render(report *ReportModel, out io.Writer) error {
+	tables, err := tabulate(report)
+	if err != nil {
+		return err
+	}
+
+	w := csv.NewWriter(out)
+	for i, t := range tables {
+		if i > 0 {
+			if err := w.Write(nil); err != nil {
+				return err
+			}
+		}
+		if err := w.Write([]string{t.name}); err != nil {
+			return err
+		}
+		if err := w.Write(t.columns); err != nil {
+			return err
+		}
+		if err := w.WriteAll(t.cells()); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// markdownRenderer renders every table under a heading holding the name of the table
+func (markdownRenderer) Render(report *ReportModel, out io.Writer) error {
+	tables, err := tabulate(report)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for i, t := range tables {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		fmt.Fprintf(&buf, "## %s\n\n", t.name)
+		writeMarkdownRow(&buf, t.columns)
+		separators := make([]string, len(t.columns))
+		for j := range separators {
+			separators[j] = "---"
+		}
+		writeMarkdownRow(&buf, separators)
+		for _, row := range t.cells() {
+			writeMarkdownRow(&buf, row)
+		}
+	}
+
+	_, err = out.Write(buf.Bytes())
+	return err
+}
+
+// writeMarkdownRow writes the cells as a row of a markdown table, escaping the pipes within the cells
+func writeMarkdownRow(buf *bytes.Buffer, cells []string) {
+	buf.WriteString("|")
+	for _, cell := range cells {
+		buf.WriteString(" " + strings.ReplaceAll(cell, "|", `\|`) + " |")
+	}
+	buf.WriteString("\n")
+}
+
+// tableRenderer renders every table with aligned columns under an upper-cased title, for reading in a terminal
+func (tableRenderer) Render(report *ReportModel, out io.Writer) error {
+	tables, err := tabulate(report)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for i, t := range tables {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, strings.ToUpper(strings.ReplaceAll(t.name, "_", " ")))
+		fmt.Fprintln(w, strings.Join(t.columns, "\t"))
+		for _, row := range t.cells() {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		// the columns of every table are aligned on their own
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// yamlNode converts a tree decoded by decodeReport into a YAML node, keeping the order of the object keys
+func yamlNode(value interface{}) *yaml.Node {
+	switch v := value.(type) {
+	case orderedObject:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		if len(v) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		for _, field := range v {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: field.key}, yamlNode(field.value))
+		}
+		return node
+	case []interface{}:
+		// sequences of values, e.g. the hours of the hourly load, are kept on a single line
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		if !hasObjects(v) {
+			node.Style = yaml.FlowStyle
+		}
+		for _, item := range v {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(string(v), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: string(v)}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: fmt.Sprint(v)}
+	}
+}
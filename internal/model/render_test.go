@@ -0,0 +1,239 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockRenderReport() *ReportModel {
+	report := NewReportModel()
+	report.SetUniqueRecipeCount(2)
+	report.SetCountPerRecipe(RecipeCounts{{Recipe: "Honey, Mustard", RecipeCount: 3}, {Recipe: "Steak", RecipeCount: 1}})
+	report.SetBusiestPostcode(PostcodeCount{Postcode: "10245", DeliveryCount: 3})
+	report.SetMatchByName(RecipeMatches{"Steak"})
+	report.SetMatchByTerm([]TermMatch{
+		{Term: "Ste", DeliveryCount: 1, Recipes: []MatchedRecipe{{Recipe: "Steak", RecipeCount: 1}}},
+		{Term: "Pie", Recipes: []MatchedRecipe{}},
+	}, []string{"Pie"})
+	report.Omit(SectionCountPerPostcodeAndTime)
+	report.SetDataQuality(DataQuality{
+		RecordsRead:     5,
+		RecordsAccepted: 4,
+		Rejections:      []Rejection{{Reason: RejectionMissingField, Count: 1, Samples: []string{"postcode"}}},
+	})
+	report.SetRejectedCount(1)
+	return report
+}
+
+func TestRenderer_Render(t *testing.T) {
+	tcs := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: FormatNDJSON,
+			want: `{"unique_recipe_count":2}
+{"count_per_recipe":[{"recipe":"Honey, Mustard","count":3},{"recipe":"Steak","count":1}]}
+{"busiest_postcode":{"postcode":"10245","delivery_count":3}}
+{"match_by_name":["Steak"]}
+{"match_by_term":[{"term":"Ste","delivery_count":1,"recipes":[{"recipe":"Steak","count":1}]},{"term":"Pie","delivery_count":0,"recipes":[]}]}
+{"unmatched_terms":["Pie"]}
+{"rejected_count":1}
+{"data_quality":{"records_read":5,"records_accepted":4,"rejections":[{"reason":"missing_field","count":1,"samples":["postcode"]}]}}
+`,
+		},
+		{
+			format: FormatYAML,
+			want: `unique_recipe_count: 2
+count_per_recipe:
+  - recipe: Honey, Mustard
+    count: 3
+  - recipe: Steak
+    count: 1
+busiest_postcode:
+  postcode: "10245"
+  delivery_count: 3
+match_by_name: [Steak]
+match_by_term:
+  - term: Ste
+    delivery_count: 1
+    recipes:
+      - recipe: Steak
+        count: 1
+  - term: Pie
+    delivery_count: 0
+    recipes: []
+unmatched_terms: [Pie]
+rejected_count: 1
+data_quality:
+  records_read: 5
+  records_accepted: 4
+  rejections:
+    - reason: missing_field
+      count: 1
+      samples: [postcode]
+`,
+		},
+		{
+			format: FormatCSV,
+			want: `unique_recipe_count
+unique_recipe_count
+2
+
+count_per_recipe
+recipe,count
+"Honey, Mustard",3
+Steak,1
+
+busiest_postcode
+postcode,delivery_count
+10245,3
+
+match_by_name
+match_by_name
+Steak
+
+match_by_term
+term,delivery_count
+Ste,1
+Pie,0
+
+match_by_term.recipes
+term,recipe,count
+Ste,Steak,1
+
+unmatched_terms
+unmatched_terms
+Pie
+
+rejected_count
+rejected_count
+1
+
+data_quality
+records_read,records_accepted
+5,4
+
+data_quality.rejections
+reason,count,samples
+missing_field,1,postcode
+`,
+		},
+		{
+			format: FormatMarkdown,
+			want: `## unique_recipe_count
+
+| unique_recipe_count |
+| --- |
+| 2 |
+
+## count_per_recipe
+
+| recipe | count |
+| --- | --- |
+| Honey, Mustard | 3 |
+| Steak | 1 |
+
+## busiest_postcode
+
+| postcode | delivery_count |
+| --- | --- |
+| 10245 | 3 |
+
+## match_by_name
+
+| match_by_name |
+| --- |
+| Steak |
+
+## match_by_term
+
+| term | delivery_count |
+| --- | --- |
+| Ste | 1 |
+| Pie | 0 |
+
+## match_by_term.recipes
+
+| term | recipe | count |
+| --- | --- | --- |
+| Ste | Steak | 1 |
+
+## unmatched_terms
+
+| unmatched_terms |
+| --- |
+| Pie |
+
+## rejected_count
+
+| rejected_count |
+| --- |
+| 1 |
+
+## data_quality
+
+| records_read | records_accepted |
+| --- | --- |
+| 5 | 4 |
+
+## data_quality.rejections
+
+| reason | count | samples |
+| --- | --- | --- |
+| missing_field | 1 | postcode |
+`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(string(tc.format), func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			assert.Nil(t, NewRenderer(tc.format).Render(mockRenderReport(), buf))
+			assert.Equal(t, tc.want, buf.String())
+		})
+	}
+}
+
+func TestRenderer_RenderTable(t *testing.T) {
+	report := NewReportModel()
+	report.SetCountPerRecipe(RecipeCounts{{Recipe: "Honey Mustard Chicken", RecipeCount: 3}, {Recipe: "Steak", RecipeCount: 12}})
+	for _, section := range []string{SectionUniqueRecipeCount, SectionBusiestPostcode, SectionCountPerPostcodeAndTime,
+		SectionMatchByName, SectionDataQuality, SectionRejectedCount} {
+		report.Omit(section)
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	assert.Nil(t, NewRenderer(FormatTable).Render(report, buf))
+
+	want := `COUNT PER RECIPE
+recipe                 count
+Honey Mustard Chicken  3
+Steak                  12
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestRenderer_RenderJSON(t *testing.T) {
+	want := bytes.NewBuffer([]byte{})
+	assert.Nil(t, mockRenderReport().Dumps(want))
+
+	got := bytes.NewBuffer([]byte{})
+	assert.Nil(t, NewRenderer(FormatJSON).Render(mockRenderReport(), got))
+	assert.Equal(t, want.String(), got.String())
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("Markdown")
+	assert.Nil(t, err)
+	assert.Equal(t, FormatMarkdown, format)
+
+	format, err = ParseFormat("html")
+	assert.Nil(t, err)
+	assert.Equal(t, FormatHTML, format)
+
+	_, err = ParseFormat("xml")
+	assert.NotNil(t, err)
+}
@@ -1,10 +1,47 @@
 package model
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
+	"sort"
 )
 
+// Names of the report sections contributed by the aggregators
+const (
+	SectionUniqueRecipeCount       = "unique_recipe_count"
+	SectionCountPerRecipe          = "count_per_recipe"
+	SectionBusiestPostcode         = "busiest_postcode"
+	SectionTopPostcodes            = "top_postcodes"
+	SectionPostcodeCounts          = "postcode_counts"
+	SectionCountPerPostcodeAndTime = "count_per_postcode_and_time"
+	SectionMatchByName             = "match_by_name"
+	SectionMatchByTerm             = "match_by_term"
+	SectionUnmatchedTerms          = "unmatched_terms"
+	SectionCountPerWeekday         = "count_per_weekday"
+	SectionHourlyLoad              = "hourly_load"
+
+	SectionRejectedCount = "rejected_count"
+	SectionDataQuality   = "data_quality"
+	SectionPerFile       = "per_file"
+	SectionIncomplete    = "incomplete"
+)
+
+// builtInSections are the sections of ReportModel, custom sections must not take their names
+var builtInSections = map[string]bool{
+	SectionUniqueRecipeCount: true, SectionCountPerRecipe: true, SectionBusiestPostcode: true,
+	SectionTopPostcodes: true, SectionPostcodeCounts: true, SectionCountPerPostcodeAndTime: true,
+	SectionMatchByName: true, SectionMatchByTerm: true, SectionUnmatchedTerms: true, SectionCountPerWeekday: true,
+	SectionHourlyLoad: true, SectionRejectedCount: true, SectionDataQuality: true, SectionPerFile: true,
+	SectionIncomplete: true,
+}
+
+// IsBuiltInSection returns whether name is the name of a section of ReportModel
+func IsBuiltInSection(name string) bool {
+	return builtInSections[name]
+}
+
 type RecipeCounts []RecipeCount
 
 type RecipeCount struct {
@@ -17,21 +54,156 @@ type PostcodeCount struct {
 	DeliveryCount int    `json:"delivery_count"`
 }
 
+// RankedPostcodeCount is a postcode ranked by its number of deliveries, Share is its percentage of all the deliveries
+type RankedPostcodeCount struct {
+	Rank          int     `json:"rank"`
+	Postcode      string  `json:"postcode"`
+	DeliveryCount int     `json:"delivery_count"`
+	Share         float64 `json:"share"`
+}
+
 type RecipeMatches []string
 
+// TermMatch lists the recipes a single match term matched with their counts, DeliveryCount is the sum of the counts.
+// a term that matched nothing has no recipes
+type TermMatch struct {
+	Term          string          `json:"term"`
+	DeliveryCount int             `json:"delivery_count"`
+	Recipes       []MatchedRecipe `json:"recipes"`
+}
+
+// MatchedRecipe is a recipe matched by a term, Score is the similarity (0 to 1) of the recipe name and the term in
+// fuzzy mode
+type MatchedRecipe struct {
+	Recipe      string  `json:"recipe"`
+	RecipeCount int     `json:"count"`
+	Score       float64 `json:"score,omitempty"`
+}
+
+// PostcodeTimeCount is the result of a postcode query, DeliveryCount counts the deliveries matching the query with
+// the first match mode. Matches details every match mode when match modes were requested
 type PostcodeTimeCount struct {
+	Postcode      string        `json:"postcode"`
+	From          string        `json:"from"`
+	To            string        `json:"to"`
+	Days          []string      `json:"days,omitempty"`
+	DeliveryCount int           `json:"delivery_count"`
+	Matches       []WindowMatch `json:"matches,omitempty"`
+}
+
+// WindowMatch counts the deliveries matching a query window with a single match mode, OverlapMinutes sums the minutes
+// these deliveries have in common with the query window
+type WindowMatch struct {
+	Mode           MatchMode `json:"mode"`
+	DeliveryCount  int       `json:"delivery_count"`
+	OverlapMinutes int       `json:"overlap_minutes"`
+}
+
+// WeekdayCount details the deliveries and the recipes delivered on a single weekday
+type WeekdayCount struct {
+	Weekday           string       `json:"weekday"`
+	DeliveryCount     int          `json:"delivery_count"`
+	UniqueRecipeCount int          `json:"unique_recipe_count"`
+	CountPerRecipe    RecipeCounts `json:"count_per_recipe"`
+}
+
+// HourlyLoad counts the deliveries active during every hour of the day, Hours[0] being 12AM - 1AM. ByWeekday details
+// the same per weekday when requested
+type HourlyLoad struct {
+	Hours     []int               `json:"hours"`
+	ByWeekday []WeekdayHourlyLoad `json:"by_weekday,omitempty"`
+}
+
+// WeekdayHourlyLoad counts the deliveries active during every hour of a single weekday
+type WeekdayHourlyLoad struct {
+	Weekday string `json:"weekday"`
+	Hours   []int  `json:"hours"`
+}
+
+// PostcodeHourlyLoad is the hourly load of the deliveries to a single postcode
+type PostcodeHourlyLoad struct {
 	Postcode      string `json:"postcode"`
-	From          string `json:"from"`
-	To            string `json:"to"`
 	DeliveryCount int    `json:"delivery_count"`
+	HourlyLoad
+}
+
+// HourlyLoadReport is the hourly load of all the deliveries and of the busiest postcodes
+type HourlyLoadReport struct {
+	HourlyLoad
+	Postcodes []PostcodeHourlyLoad `json:"postcodes"`
+}
+
+// FileStats details what a single input file contributed to the report
+type FileStats struct {
+	File     string `json:"file"`
+	Records  int    `json:"records"`
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+}
+
+// RejectionReason groups rejected records by the check they failed
+type RejectionReason string
+
+const (
+	RejectionMalformedRecord   RejectionReason = "malformed_record"
+	RejectionMissingField      RejectionReason = "missing_field"
+	RejectionInvalidDelivery   RejectionReason = "unparseable_delivery"
+	RejectionPostcodeTooLong   RejectionReason = "postcode_too_long"
+	RejectionRecipeNameTooLong RejectionReason = "recipe_name_too_long"
+)
+
+// RejectionReasons lists all the reasons in the order they are reported
+var RejectionReasons = []RejectionReason{
+	RejectionMalformedRecord,
+	RejectionMissingField,
+	RejectionInvalidDelivery,
+	RejectionPostcodeTooLong,
+	RejectionRecipeNameTooLong,
+}
+
+// Rejection counts the records rejected for a single reason, Samples holds a few of the offending values
+type Rejection struct {
+	Reason  RejectionReason `json:"reason"`
+	Count   int             `json:"count"`
+	Samples []string        `json:"samples"`
+}
+
+// DataQuality details how many of the input records made it into the report and why the rest did not
+type DataQuality struct {
+	RecordsRead     int         `json:"records_read"`
+	RecordsAccepted int         `json:"records_accepted"`
+	Rejections      []Rejection `json:"rejections"`
 }
 
 type ReportModel struct {
-	UniqueRecipeCount       int               `json:"unique_recipe_count"`
-	CountPerRecipe          RecipeCounts      `json:"count_per_recipe"`
-	BusiestPostcode         PostcodeCount     `json:"busiest_postcode"`
-	CountPerPostcodeAndTime PostcodeTimeCount `json:"count_per_postcode_and_time"`
-	MatchByName             RecipeMatches     `json:"match_by_name"`
+	UniqueRecipeCount       int                   `json:"unique_recipe_count"`
+	CountPerRecipe          RecipeCounts          `json:"count_per_recipe"`
+	BusiestPostcode         PostcodeCount         `json:"busiest_postcode"`
+	TopPostcodes            []RankedPostcodeCount `json:"top_postcodes,omitempty"`
+	PostcodeCounts          []PostcodeCount       `json:"postcode_counts,omitempty"`
+	CountPerPostcodeAndTime []PostcodeTimeCount   `json:"count_per_postcode_and_time"`
+	MatchByName             RecipeMatches         `json:"match_by_name"`
+	MatchByTerm             []TermMatch           `json:"match_by_term,omitempty"`
+	UnmatchedTerms          []string              `json:"unmatched_terms,omitempty"`
+	CountPerWeekday         []WeekdayCount        `json:"count_per_weekday,omitempty"`
+	HourlyLoad              *HourlyLoadReport     `json:"hourly_load,omitempty"`
+	RejectedCount           int                   `json:"rejected_count"`
+	DataQuality             DataQuality           `json:"data_quality"`
+	PerFile                 []FileStats           `json:"per_file,omitempty"`
+	Incomplete              bool                  `json:"incomplete,omitempty"`
+
+	// singleQuery keeps the shape of reports that supported a single postcode query
+	singleQuery bool
+
+	// omitted holds the sections left out of the report, custom holds the sections contributed by custom aggregators
+	omitted map[string]bool
+	custom  map[string]interface{}
+}
+
+// reportSection is a top level key of the encoded report with its value
+type reportSection struct {
+	name  string
+	value interface{}
 }
 
 // NewReportModel represents the final model used as output in this application
@@ -39,6 +211,144 @@ func NewReportModel() *ReportModel {
 	return &ReportModel{}
 }
 
+// MarshalJSON encodes the report sections in the order of the fields of ReportModel, leaving out the omitted sections
+// and the optional sections that are empty. custom sections follow the aggregator sections in ascending order of their
+// name. with single query compatibility count_per_postcode_and_time is encoded as the object of the first query rather
+// than as an array of all the queries
+func (rm *ReportModel) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, section := range rm.sections() {
+		value, err := json.Marshal(section.value)
+		if err != nil {
+			return nil, fmt.Errorf("failed encoding report section %s: %w", section.name, err)
+		}
+		name, _ := json.Marshal(section.name)
+
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a report encoded by MarshalJSON. the sections missing from data are omitted, so that the report
+// is encoded again the way it was read. count_per_postcode_and_time holding a single object enables single query
+// compatibility and unknown sections are kept as custom sections
+func (rm *ReportModel) UnmarshalJSON(data []byte) error {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return err
+	}
+
+	*rm = ReportModel{}
+	fields := map[string]interface{}{
+		SectionUniqueRecipeCount:       &rm.UniqueRecipeCount,
+		SectionCountPerRecipe:          &rm.CountPerRecipe,
+		SectionBusiestPostcode:         &rm.BusiestPostcode,
+		SectionTopPostcodes:            &rm.TopPostcodes,
+		SectionPostcodeCounts:          &rm.PostcodeCounts,
+		SectionCountPerPostcodeAndTime: &rm.CountPerPostcodeAndTime,
+		SectionMatchByName:             &rm.MatchByName,
+		SectionMatchByTerm:             &rm.MatchByTerm,
+		SectionUnmatchedTerms:          &rm.UnmatchedTerms,
+		SectionCountPerWeekday:         &rm.CountPerWeekday,
+		SectionHourlyLoad:              &rm.HourlyLoad,
+		SectionRejectedCount:           &rm.RejectedCount,
+		SectionDataQuality:             &rm.DataQuality,
+		SectionPerFile:                 &rm.PerFile,
+		SectionIncomplete:              &rm.Incomplete,
+	}
+
+	if query := bytes.TrimSpace(sections[SectionCountPerPostcodeAndTime]); len(query) > 0 && query[0] == '{' {
+		rm.CountPerPostcodeAndTime = make([]PostcodeTimeCount, 1)
+		fields[SectionCountPerPostcodeAndTime] = &rm.CountPerPostcodeAndTime[0]
+		rm.singleQuery = true
+	}
+
+	for name, value := range sections {
+		field, ok := fields[name]
+		if !ok {
+			dec := json.NewDecoder(bytes.NewReader(value))
+			dec.UseNumber()
+			var custom interface{}
+			if err := dec.Decode(&custom); err != nil {
+				return fmt.Errorf("failed decoding report section %s: %w", name, err)
+			}
+			rm.SetSection(name, custom)
+			continue
+		}
+		if err := json.Unmarshal(value, field); err != nil {
+			return fmt.Errorf("failed decoding report section %s: %w", name, err)
+		}
+	}
+
+	// the optional sections are left out when empty anyway
+	for _, name := range []string{SectionUniqueRecipeCount, SectionCountPerRecipe, SectionBusiestPostcode,
+		SectionCountPerPostcodeAndTime, SectionMatchByName, SectionRejectedCount, SectionDataQuality} {
+		if _, ok := sections[name]; !ok {
+			rm.Omit(name)
+		}
+	}
+	return nil
+}
+
+// ReadReport decodes a report written as JSON by Dumps
+func ReadReport(in io.Reader) (*ReportModel, error) {
+	report := NewReportModel()
+	if err := json.NewDecoder(in).Decode(report); err != nil {
+		return nil, fmt.Errorf("failed decoding report: %w", err)
+	}
+	return report, nil
+}
+
+// Omitted returns whether a section is left out of the report
+func (rm *ReportModel) Omitted(section string) bool {
+	return rm.omitted[section]
+}
+
+// sections returns the sections of the report in the order they are encoded
+func (rm *ReportModel) sections() []reportSection {
+	var sections []reportSection
+	add := func(name string, value interface{}, include bool) {
+		if include && !rm.omitted[name] {
+			sections = append(sections, reportSection{name: name, value: value})
+		}
+	}
+
+	var countPerPostcodeAndTime interface{} = rm.CountPerPostcodeAndTime
+	if rm.singleQuery && len(rm.CountPerPostcodeAndTime) > 0 {
+		countPerPostcodeAndTime = rm.CountPerPostcodeAndTime[0]
+	}
+
+	add(SectionUniqueRecipeCount, rm.UniqueRecipeCount, true)
+	add(SectionCountPerRecipe, rm.CountPerRecipe, true)
+	add(SectionBusiestPostcode, rm.BusiestPostcode, true)
+	add(SectionTopPostcodes, rm.TopPostcodes, len(rm.TopPostcodes) > 0)
+	add(SectionPostcodeCounts, rm.PostcodeCounts, len(rm.PostcodeCounts) > 0)
+	add(SectionCountPerPostcodeAndTime, countPerPostcodeAndTime, true)
+	add(SectionMatchByName, rm.MatchByName, true)
+	add(SectionMatchByTerm, rm.MatchByTerm, rm.MatchByTerm != nil)
+	add(SectionUnmatchedTerms, rm.UnmatchedTerms, rm.MatchByTerm != nil)
+	add(SectionCountPerWeekday, rm.CountPerWeekday, len(rm.CountPerWeekday) > 0)
+	add(SectionHourlyLoad, rm.HourlyLoad, rm.HourlyLoad != nil)
+
+	for _, name := range rm.CustomSections() {
+		add(name, rm.custom[name], true)
+	}
+
+	add(SectionRejectedCount, rm.RejectedCount, true)
+	add(SectionDataQuality, rm.DataQuality, true)
+	add(SectionPerFile, rm.PerFile, len(rm.PerFile) > 0)
+	add(SectionIncomplete, rm.Incomplete, rm.Incomplete)
+
+	return sections
+}
+
 // Dumps outputs the data into file or any other io.Writer
 func (rm *ReportModel) Dumps(out io.Writer) error {
 	bs, err := json.MarshalIndent(rm, "", " ")
@@ -65,10 +375,95 @@ func (rm *ReportModel) SetBusiestPostcode(postcodeCount PostcodeCount) {
 	rm.BusiestPostcode = postcodeCount
 }
 
-func (rm *ReportModel) SetCountPerPostcodeAndTime(postcodeTimeCount PostcodeTimeCount) {
-	rm.CountPerPostcodeAndTime = postcodeTimeCount
+func (rm *ReportModel) SetTopPostcodes(topPostcodes []RankedPostcodeCount) {
+	rm.TopPostcodes = topPostcodes
+}
+
+// SetPostcodeCounts sets the deliveries of every postcode, which makes the busiest and top postcodes of reports
+// mergeable
+func (rm *ReportModel) SetPostcodeCounts(postcodeCounts []PostcodeCount) {
+	rm.PostcodeCounts = postcodeCounts
+}
+
+func (rm *ReportModel) SetCountPerPostcodeAndTime(postcodeTimeCounts []PostcodeTimeCount) {
+	rm.CountPerPostcodeAndTime = postcodeTimeCounts
+}
+
+// SetSingleQueryCompat encodes count_per_postcode_and_time as the object of the first query, the way reports were
+// shaped before multiple queries were supported
+func (rm *ReportModel) SetSingleQueryCompat(singleQuery bool) {
+	rm.singleQuery = singleQuery
+}
+
+// SingleQueryCompat returns whether count_per_postcode_and_time is encoded as the object of the first query
+func (rm *ReportModel) SingleQueryCompat() bool {
+	return rm.singleQuery
 }
 
 func (rm *ReportModel) SetMatchByName(recipeMatches RecipeMatches) {
 	rm.MatchByName = recipeMatches
 }
+
+// SetMatchByTerm sets the recipes matched by every term, unmatchedTerms lists the terms that matched no recipe. both
+// are left out of the report when termMatches is nil
+func (rm *ReportModel) SetMatchByTerm(termMatches []TermMatch, unmatchedTerms []string) {
+	rm.MatchByTerm = termMatches
+	rm.UnmatchedTerms = unmatchedTerms
+}
+
+func (rm *ReportModel) SetCountPerWeekday(weekdayCounts []WeekdayCount) {
+	rm.CountPerWeekday = weekdayCounts
+}
+
+func (rm *ReportModel) SetHourlyLoad(hourlyLoad *HourlyLoadReport) {
+	rm.HourlyLoad = hourlyLoad
+}
+
+func (rm *ReportModel) SetRejectedCount(cnt int) {
+	rm.RejectedCount = cnt
+}
+
+func (rm *ReportModel) SetDataQuality(dataQuality DataQuality) {
+	rm.DataQuality = dataQuality
+}
+
+func (rm *ReportModel) SetPerFile(fileStats []FileStats) {
+	rm.PerFile = fileStats
+}
+
+func (rm *ReportModel) SetIncomplete(incomplete bool) {
+	rm.Incomplete = incomplete
+}
+
+// Omit leaves a section out of the report, e.g. the section of an aggregator that was not selected
+func (rm *ReportModel) Omit(section string) {
+	if rm.omitted == nil {
+		rm.omitted = make(map[string]bool)
+	}
+	rm.omitted[section] = true
+}
+
+// SetSection adds a custom section to the report, it is encoded after the sections of the built-in aggregators.
+// the name must not be the one of a built-in section
+func (rm *ReportModel) SetSection(name string, value interface{}) {
+	if rm.custom == nil {
+		rm.custom = make(map[string]interface{})
+	}
+	rm.custom[name] = value
+}
+
+// CustomSections returns the names of the custom sections in ascending order
+func (rm *ReportModel) CustomSections() []string {
+	names := make([]string, 0, len(rm.custom))
+	for name := range rm.custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Section returns the value of a custom section and whether the report has it
+func (rm *ReportModel) Section(name string) (interface{}, bool) {
+	value, ok := rm.custom[name]
+	return value, ok
+}
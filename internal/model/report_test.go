@@ -2,6 +2,7 @@ package model
 
 import (
 	"bytes"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -19,13 +20,114 @@ func TestReport_Dumps(t *testing.T) {
   "postcode": "",
   "delivery_count": 0
  },
- "count_per_postcode_and_time": {
-  "postcode": "",
-  "from": "",
-  "to": "",
-  "delivery_count": 0
- },
- "match_by_name": null
+ "count_per_postcode_and_time": null,
+ "match_by_name": null,
+ "rejected_count": 0,
+ "data_quality": {
+  "records_read": 0,
+  "records_accepted": 0,
+  "rejections": null
+ }
 }`
 	assert.JSONEq(t, expected, buf.String())
 }
+
+func TestReport_DumpsSingleQueryCompat(t *testing.T) {
+	tcs := []struct {
+		name        string
+		singleQuery bool
+		want        string
+	}{
+		{
+			name:        "array of all the queries",
+			singleQuery: false,
+			want: `[{"postcode": "10120", "from": "10AM", "to": "3PM", "delivery_count": 4},
+				{"postcode": "10245", "from": "1PM", "to": "6PM", "delivery_count": 2}]`,
+		},
+		{
+			name:        "object of the first query",
+			singleQuery: true,
+			want:        `{"postcode": "10120", "from": "10AM", "to": "3PM", "delivery_count": 4}`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			report := NewReportModel()
+			report.SetCountPerPostcodeAndTime([]PostcodeTimeCount{
+				{Postcode: "10120", From: "10AM", To: "3PM", DeliveryCount: 4},
+				{Postcode: "10245", From: "1PM", To: "6PM", DeliveryCount: 2},
+			})
+			report.SetSingleQueryCompat(tc.singleQuery)
+
+			buf := bytes.NewBuffer([]byte{})
+			assert.Nil(t, report.Dumps(buf))
+
+			var got map[string]json.RawMessage
+			assert.Nil(t, json.Unmarshal(buf.Bytes(), &got))
+			assert.JSONEq(t, tc.want, string(got["count_per_postcode_and_time"]))
+			assert.Contains(t, got, "data_quality")
+		})
+	}
+}
+
+func TestReport_DumpsSections(t *testing.T) {
+	report := NewReportModel()
+	report.SetUniqueRecipeCount(2)
+	report.SetSection("recipe_name_length", 12)
+	report.SetSection("average_delivery_minutes", 90)
+	report.Omit(SectionCountPerRecipe)
+	report.Omit(SectionBusiestPostcode)
+	report.Omit(SectionCountPerPostcodeAndTime)
+	report.Omit(SectionMatchByName)
+
+	buf := bytes.NewBuffer([]byte{})
+	assert.Nil(t, report.Dumps(buf))
+
+	// custom sections follow the aggregator sections ordered by name
+	want := `{
+ "unique_recipe_count": 2,
+ "average_delivery_minutes": 90,
+ "recipe_name_length": 12,
+ "rejected_count": 0,
+ "data_quality": {
+  "records_read": 0,
+  "records_accepted": 0,
+  "rejections": null
+ }
+}`
+	assert.Equal(t, want, buf.String())
+
+	value, ok := report.Section("recipe_name_length")
+	assert.True(t, ok)
+	assert.Equal(t, 12, value)
+}
+
+func TestReadReport(t *testing.T) {
+	report := NewReportModel()
+	report.SetUniqueRecipeCount(2)
+	report.SetCountPerRecipe(RecipeCounts{{Recipe: "Steak", RecipeCount: 1}, {Recipe: "Tacos", RecipeCount: 3}})
+	report.SetCountPerPostcodeAndTime([]PostcodeTimeCount{{Postcode: "10120", From: "10AM", To: "3PM", DeliveryCount: 4}})
+	report.SetSingleQueryCompat(true)
+	report.SetHourlyLoad(&HourlyLoadReport{HourlyLoad: HourlyLoad{Hours: []int{1, 2}}, Postcodes: []PostcodeHourlyLoad{}})
+	report.SetSection("recipe_name_length", 12)
+	report.Omit(SectionMatchByName)
+
+	buf := bytes.NewBuffer([]byte{})
+	assert.Nil(t, report.Dumps(buf))
+	want := buf.String()
+
+	got, err := ReadReport(bytes.NewReader(buf.Bytes()))
+	assert.Nil(t, err)
+	assert.Equal(t, report.CountPerPostcodeAndTime, got.CountPerPostcodeAndTime)
+	assert.Equal(t, report.HourlyLoad, got.HourlyLoad)
+	assert.True(t, got.Omitted(SectionMatchByName))
+
+	// the report is encoded again the way it was read
+	buf.Reset()
+	assert.Nil(t, got.Dumps(buf))
+	assert.Equal(t, want, buf.String())
+
+	_, err = ReadReport(bytes.NewReader([]byte(`{"unique_recipe_count": "2"}`)))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,248 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// orderedObject is a decoded JSON object keeping the order of its keys
+type orderedObject []orderedField
+
+type orderedField struct {
+	key   string
+	value interface{}
+}
+
+// decodeReport encodes report as JSON and decodes it back into a tree of orderedObject, []interface{} and scalars
+// (json.Number, string, bool and nil), so that the sections keep the order, omissions and custom sections of the JSON
+// report
+func decodeReport(report *ReportModel) (interface{}, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+// decodeValue decodes the next value of dec
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		obj := orderedObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, orderedField{key: fmt.Sprint(key), value: value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err = dec.Token()
+		return arr, err
+	default:
+		return token, nil
+	}
+}
+
+// table is a flat rendering of (part of) a report section, columns are ordered by their first appearance
+type table struct {
+	name    string
+	columns []string
+	rows    []map[string]string
+}
+
+// cell is the value of a single column of a row
+type cell struct {
+	column string
+	value  string
+}
+
+// add adds a row to the table, adding the columns the table does not have yet
+func (t *table) add(row []cell) {
+	values := make(map[string]string, len(row))
+	for _, c := range row {
+		if _, ok := values[c.column]; ok {
+			continue
+		}
+		if !t.hasColumn(c.column) {
+			t.columns = append(t.columns, c.column)
+		}
+		values[c.column] = c.value
+	}
+	t.rows = append(t.rows, values)
+}
+
+func (t *table) hasColumn(column string) bool {
+	for _, c := range t.columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// cells returns the values of every row in the order of the columns, missing values are empty
+func (t *table) cells() [][]string {
+	cells := make([][]string, 0, len(t.rows))
+	for _, row := range t.rows {
+		values := make([]string, 0, len(t.columns))
+		for _, column := range t.columns {
+			values = append(values, row[column])
+		}
+		cells = append(cells, values)
+	}
+	return cells
+}
+
+// tabulator collects the tables of a report in the order they are created
+type tabulator struct {
+	tables []*table
+	byName map[string]*table
+}
+
+// tabulate renders every section of report as tables, for the formats that cannot hold nested data. an array section
+// has a row per item, any other section a single row. nested objects become columns named by their path (e.g.
+// busiest_postcode.postcode), arrays of values are joined by ';' and arrays of objects become tables of their own
+// (e.g. count_per_weekday.count_per_recipe) whose rows start with the first column of the row they belong to
+func tabulate(report *ReportModel) ([]*table, error) {
+	tree, err := decodeReport(report)
+	if err != nil {
+		return nil, err
+	}
+	sections, ok := tree.(orderedObject)
+	if !ok {
+		return nil, fmt.Errorf("report is not encoded as an object")
+	}
+
+	tb := &tabulator{byName: make(map[string]*table)}
+	for _, section := range sections {
+		if items, ok := section.value.([]interface{}); ok {
+			t := tb.table(section.key)
+			for _, item := range items {
+				tb.addRow(t, item, nil, true)
+			}
+			continue
+		}
+		tb.addRow(tb.table(section.key), section.value, nil, false)
+	}
+
+	for _, t := range tb.tables {
+		if len(t.columns) == 0 {
+			t.columns = []string{t.name}
+		}
+	}
+	return tb.tables, nil
+}
+
+// table returns the table called name, creating it when there is none yet
+func (tb *tabulator) table(name string) *table {
+	if t, ok := tb.byName[name]; ok {
+		return t
+	}
+	t := &table{name: name}
+	tb.byName[name] = t
+	tb.tables = append(tb.tables, t)
+	return t
+}
+
+// addRow adds value as a row of t, starting with the parent cells. when the row is one of many (an array item) its
+// first cell is added to the parent cells of its nested tables, so that their rows can be told apart
+func (tb *tabulator) addRow(t *table, value interface{}, parent []cell, item bool) {
+	row := append([]cell(nil), parent...)
+
+	obj, ok := value.(orderedObject)
+	if !ok {
+		t.add(append(row, cell{column: t.name[strings.LastIndex(t.name, ".")+1:], value: cellValue(value)}))
+		return
+	}
+
+	var nested []orderedField
+	row = flatten(row, "", obj, &nested)
+	t.add(row)
+
+	nestedParent := parent
+	if item && len(row) > len(parent) {
+		nestedParent = row[:len(parent)+1]
+	}
+	for _, field := range nested {
+		child := tb.table(t.name + "." + field.key)
+		for _, childItem := range field.value.([]interface{}) {
+			tb.addRow(child, childItem, nestedParent, true)
+		}
+	}
+}
+
+// flatten appends the cells of obj to row. nested objects become cells prefixed by their key, arrays of values are
+// joined by ';', arrays of objects are collected in nested and empty arrays are skipped
+func flatten(row []cell, prefix string, obj orderedObject, nested *[]orderedField) []cell {
+	for _, field := range obj {
+		key := prefix + field.key
+		switch v := field.value.(type) {
+		case orderedObject:
+			row = flatten(row, key+".", v, nested)
+		case []interface{}:
+			// an empty array could hold values as well as objects, the column is left out
+			if len(v) == 0 {
+				continue
+			}
+			if hasObjects(v) {
+				*nested = append(*nested, orderedField{key: key, value: v})
+				continue
+			}
+			row = append(row, cell{column: key, value: cellValue(v)})
+		default:
+			row = append(row, cell{column: key, value: cellValue(v)})
+		}
+	}
+	return row
+}
+
+// hasObjects returns whether any of the items is an object
+func hasObjects(items []interface{}) bool {
+	for _, item := range items {
+		if _, ok := item.(orderedObject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cellValue renders a decoded value as the text of a cell, arrays are joined by ';' and null is empty
+func cellValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, cellValue(item))
+		}
+		return strings.Join(values, ";")
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,232 @@
+package model
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// builtInTemplates are the templates bundled with the binary, they can be passed by name instead of a file
+//
+//go:embed templates/*
+var builtInTemplates embed.FS
+
+// BuiltInTemplates lists the names of the bundled templates
+func BuiltInTemplates() []string {
+	entries, _ := builtInTemplates.ReadDir("templates")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+// TemplateData is what templates are executed with. it embeds the report, so that its fields are available as they
+// are (e.g. {{ .BusiestPostcode.Postcode }}), Sections holds the custom sections by name. RecipeChart and
+// PostcodeChart lay out the bar charts of the html format
+type TemplateData struct {
+	*ReportModel
+	Sections map[string]interface{}
+}
+
+// executor is implemented by text/template and html/template alike
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// templateRenderer renders the report with a Go template
+type templateRenderer struct {
+	tmpl executor
+}
+
+// NewTemplateRenderer parses the template of file, or the bundled template of that name (see BuiltInTemplates). files
+// named *.html or *.htm (optionally followed by .tmpl) are parsed with html/template, escaping the report values, any
+// other file with text/template. both can use the TemplateFuncs
+func NewTemplateRenderer(file string) (Renderer, error) {
+	text, err := readTemplate(file)
+	if err != nil {
+		return nil, err
+	}
+
+	name := path.Base(file)
+	var tmpl executor
+	switch ext := path.Ext(strings.TrimSuffix(name, ".tmpl")); ext {
+	case ".html", ".htm":
+		tmpl, err = htmltemplate.New(name).Funcs(htmltemplate.FuncMap(TemplateFuncs())).Parse(text)
+	default:
+		tmpl, err = texttemplate.New(name).Funcs(TemplateFuncs()).Parse(text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing template %s: %w", file, err)
+	}
+	return templateRenderer{tmpl: tmpl}, nil
+}
+
+// readTemplate reads the bundled template called name, or the file name when there is no such template
+func readTemplate(name string) (string, error) {
+	for _, builtIn := range BuiltInTemplates() {
+		if name == builtIn {
+			data, err := builtInTemplates.ReadFile("templates/" + name)
+			return string(data), err
+		}
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("failed reading template, neither a file nor one of %v: %w", BuiltInTemplates(), err)
+	}
+	return string(data), nil
+}
+
+func (r templateRenderer) Render(report *ReportModel, out io.Writer) error {
+	data := TemplateData{ReportModel: report, Sections: make(map[string]interface{}, len(report.custom))}
+	for name, value := range report.custom {
+		data.Sections[name] = value
+	}
+
+	if err := r.tmpl.Execute(out, data); err != nil {
+		return fmt.Errorf("failed rendering template: %w", err)
+	}
+	return nil
+}
+
+// TemplateFuncs returns the functions available to templates:
+//   - sort LIST sorts a list of values in ascending order
+//   - sortBy FIELD LIST and sortByDesc FIELD LIST sort a list of structs by one of their fields, e.g.
+//     {{ range sortByDesc "RecipeCount" .CountPerRecipe }}
+//   - percentage PART TOTAL returns PART as percentage of TOTAL rounded to 2 decimals, 0 when TOTAL is 0
+//   - padLeft WIDTH VALUE and padRight WIDTH VALUE pad VALUE with spaces to WIDTH characters, e.g.
+//     {{ .Recipe | padRight 40 }}
+//   - add A B returns the sum of the integers A and B
+func TemplateFuncs() texttemplate.FuncMap {
+	return texttemplate.FuncMap{
+		"sort": func(list interface{}) ([]interface{}, error) {
+			return sortList(list, "", false)
+		},
+		"sortBy": func(field string, list interface{}) ([]interface{}, error) {
+			return sortList(list, field, false)
+		},
+		"sortByDesc": func(field string, list interface{}) ([]interface{}, error) {
+			return sortList(list, field, true)
+		},
+		"percentage": percentage,
+		"padLeft": func(width int, value interface{}) string {
+			return fmt.Sprintf("%*v", width, value)
+		},
+		"padRight": func(width int, value interface{}) string {
+			return fmt.Sprintf("%-*v", width, value)
+		},
+		"add": func(a, b int) int {
+			return a + b
+		},
+	}
+}
+
+// sortList returns the items of list sorted by field, or by the items themselves when field is empty. items that are
+// equal keep their order
+func sortList(list interface{}, field string, desc bool) ([]interface{}, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot sort %T, must be a list", list)
+	}
+
+	items := make([]interface{}, v.Len())
+	keys := make([]reflect.Value, v.Len())
+	for i := range items {
+		item := v.Index(i)
+		items[i] = item.Interface()
+
+		key := reflect.Indirect(item)
+		if field != "" {
+			if key.Kind() != reflect.Struct {
+				return nil, fmt.Errorf("cannot sort %s by %s, must be a list of structs", v.Type(), field)
+			}
+			key = key.FieldByName(field)
+			if !key.IsValid() {
+				return nil, fmt.Errorf("cannot sort %s by %s, no such field", v.Type(), field)
+			}
+		}
+		keys[i] = key
+	}
+
+	var err error
+	indices := make([]int, len(items))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		cmp, cmpErr := compareValues(keys[indices[i]], keys[indices[j]])
+		if cmpErr != nil {
+			err = cmpErr
+		}
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]interface{}, len(items))
+	for i, index := range indices {
+		sorted[i] = items[index]
+	}
+	return sorted, nil
+}
+
+// compareValues compares two numbers or two strings, returning -1, 0 or 1
+func compareValues(a, b reflect.Value) (int, error) {
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), nil
+	}
+
+	x, okX := toFloat(a)
+	y, okY := toFloat(b)
+	if !okX || !okY {
+		return 0, fmt.Errorf("cannot compare %s and %s", a.Type(), b.Type())
+	}
+	switch {
+	case x < y:
+		return -1, nil
+	case x > y:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// toFloat converts any integer or float to float64
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// percentage returns part as percentage of total rounded to 2 decimals, both can be any integer or float
+func percentage(part, total interface{}) (float64, error) {
+	p, okP := toFloat(reflect.ValueOf(part))
+	t, okT := toFloat(reflect.ValueOf(total))
+	if !okP || !okT {
+		return 0, fmt.Errorf("cannot compute the percentage of %T and %T, must be numbers", part, total)
+	}
+	if t == 0 {
+		return 0, nil
+	}
+	return math.Round(p/t*10000) / 100, nil
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func renderTemplate(t *testing.T, name, text string, report *ReportModel) (string, error) {
+	file := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(file, []byte(text), 0o600))
+
+	renderer, err := NewTemplateRenderer(file)
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = renderer.Render(report, buf)
+	return buf.String(), err
+}
+
+func TestTemplateRenderer_Render(t *testing.T) {
+	report := mockRenderReport()
+	report.SetSection("recipe_name_length", 12)
+
+	tcs := []struct {
+		name     string
+		file     string
+		template string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "sorting and padding",
+			file: "recipes.txt",
+			template: `{{ range sortByDesc "Recipe" .CountPerRecipe }}{{ .Recipe | padRight 16 }}|{{ .RecipeCount | padLeft 3 }}
+{{ end }}`,
+			want: "Steak           |  1\nHoney, Mustard  |  3\n",
+		},
+		{
+			name:     "sorting values",
+			file:     "terms.txt",
+			template: `{{ range sort .UnmatchedTerms }}{{ . }}{{ end }},{{ range sort .MatchByName }}{{ . }}{{ end }}`,
+			want:     "Pie,Steak",
+		},
+		{
+			name:     "sorting structs without a field",
+			file:     "terms.txt",
+			template: `{{ range sort .MatchByTerm }}{{ . }}{{ end }}`,
+			wantErr:  true,
+		},
+		{
+			name:     "percentage and custom sections",
+			file:     "share.tmpl",
+			template: `{{ percentage .BusiestPostcode.DeliveryCount .DataQuality.RecordsRead }}% {{ .Sections.recipe_name_length }}`,
+			want:     "60% 12",
+		},
+		{
+			name:     "html template",
+			file:     "recipes.html.tmpl",
+			template: `{{ range .CountPerRecipe }}<li>{{ .Recipe }}</li>{{ end }}`,
+			want:     "<li>Honey, Mustard</li><li>Steak</li>",
+		},
+		{
+			name:     "sorting by an unknown field",
+			file:     "recipes.txt",
+			template: `{{ range sortBy "Name" .CountPerRecipe }}{{ end }}`,
+			wantErr:  true,
+		},
+		{
+			name:     "syntax error",
+			file:     "recipes.txt",
+			template: `{{ range .CountPerRecipe }}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := renderTemplate(t, tc.file, tc.template, report)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestTemplateRenderer_RenderEscapesHTML(t *testing.T) {
+	report := NewReportModel()
+	report.SetMatchByName(RecipeMatches{"Mac & <Cheese>"})
+
+	got, err := renderTemplate(t, "matches.html", `{{ range .MatchByName }}<li>{{ . }}</li>{{ end }}`, report)
+	assert.Nil(t, err)
+	assert.Equal(t, "<li>Mac &amp; &lt;Cheese&gt;</li>", got)
+
+	got, err = renderTemplate(t, "matches.md", `{{ range .MatchByName }}- {{ . }}{{ end }}`, report)
+	assert.Nil(t, err)
+	assert.Equal(t, "- Mac & <Cheese>", got)
+}
+
+func TestBuiltInTemplates(t *testing.T) {
+	assert.Equal(t, []string{"report.html", "summary.md"}, BuiltInTemplates())
+
+	for _, name := range BuiltInTemplates() {
+		t.Run(name, func(t *testing.T) {
+			renderer, err := NewTemplateRenderer(name)
+			assert.Nil(t, err)
+
+			buf := bytes.NewBuffer([]byte{})
+			assert.Nil(t, renderer.Render(mockRenderReport(), buf))
+			assert.Contains(t, buf.String(), "10245")
+			assert.Contains(t, buf.String(), "missing_field")
+		})
+	}
+
+	_, err := NewTemplateRenderer("summary.txt")
+	assert.NotNil(t, err)
+}
+
+func TestPercentage(t *testing.T) {
+	got, err := percentage(1, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 33.33, got)
+
+	got, err = percentage(2.5, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, got)
+
+	_, err = percentage("1", 3)
+	assert.NotNil(t, err)
+}
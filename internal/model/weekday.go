@@ -0,0 +1,41 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidWeekday = errors.New("invalid weekday")
+
+// Weekdays lists the days of the week in the order they are reported, starting on Monday
+var Weekdays = []time.Weekday{
+	time.Monday,
+	time.Tuesday,
+	time.Wednesday,
+	time.Thursday,
+	time.Friday,
+	time.Saturday,
+	time.Sunday,
+}
+
+// weekdayNames maps the lower-cased full (monday) and abbreviated (mon) day names to their weekday
+var weekdayNames = func() map[string]time.Weekday {
+	names := make(map[string]time.Weekday, 2*len(Weekdays))
+	for _, weekday := range Weekdays {
+		name := strings.ToLower(weekday.String())
+		names[name] = weekday
+		names[name[:3]] = weekday
+	}
+	return names
+}()
+
+// ParseWeekday parses a full (Monday) or abbreviated (Mon) English day name, case-insensitively
+func ParseWeekday(input string) (time.Weekday, error) {
+	weekday, ok := weekdayNames[strings.ToLower(strings.TrimSpace(input))]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q is not a day name", ErrInvalidWeekday, input)
+	}
+	return weekday, nil
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestParseWeekday(t *testing.T) {
+	tcs := []struct {
+		name    string
+		input   string
+		want    time.Weekday
+		wantErr bool
+	}{
+		{
+			name:  "full day name",
+			input: "Wednesday",
+			want:  time.Wednesday,
+		},
+		{
+			name:  "abbreviated day name",
+			input: "Sun",
+			want:  time.Sunday,
+		},
+		{
+			name:  "case insensitive",
+			input: "tHURSDAY",
+			want:  time.Thursday,
+		},
+		{
+			name:    "not a day name",
+			input:   "Someday",
+			wantErr: true,
+		},
+		{
+			name:    "partially abbreviated day name",
+			input:   "Thurs",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseWeekday(tc.input)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidWeekday)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
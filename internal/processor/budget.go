@@ -0,0 +1,109 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+)
+
+// Error budget violations
+const (
+	ViolationMaxErrors    = "max_errors"
+	ViolationMaxErrorRate = "max_error_rate"
+)
+
+// ErrorBudget bounds the invalid records tolerated by the processor. a negative value disables a threshold
+type ErrorBudget struct {
+	// MaxErrors is the number of rejected records tolerated, processing is aborted as soon as it is exceeded.
+	// strict mode is MaxErrors of 0
+	MaxErrors int
+
+	// MaxErrorRate is the ratio (0 to 1) of rejected to read records tolerated, evaluated once all input was read
+	MaxErrorRate float64
+}
+
+// UnlimitedErrorBudget tolerates any number of invalid records
+var UnlimitedErrorBudget = ErrorBudget{MaxErrors: -1, MaxErrorRate: -1}
+
+// ErrorBudgetError is a structured summary of the invalid records returned when the error budget is exceeded
+type ErrorBudgetError struct {
+	Violation     string                `json:"violation"`
+	Threshold     float64               `json:"threshold"`
+	Rejected      int                   `json:"rejected"`
+	RecordsRead   int                   `json:"records_read"`
+	ErrorRate     float64               `json:"error_rate"`
+	FirstRejected *model.RejectedRecipe `json:"first_rejected"`
+	DataQuality   model.DataQuality     `json:"data_quality"`
+}
+
+func (e *ErrorBudgetError) Error() string {
+	if e.Violation == ViolationMaxErrorRate {
+		return fmt.Sprintf("error budget exceeded: error rate %.4f is above the maximum of %.4f (%d of %d records rejected)",
+			e.ErrorRate, e.Threshold, e.Rejected, e.RecordsRead)
+	}
+	return fmt.Sprintf("error budget exceeded: %d records rejected, at most %d allowed. first rejected record: %s",
+		e.Rejected, int(e.Threshold), e.FirstRejected.Reason)
+}
+
+// SetErrorBudget sets the invalid records tolerated by the processor, by default any number is tolerated
+func (p *Processor) SetErrorBudget(budget ErrorBudget) {
+	p.budget = budget
+}
+
+// isAborted whether processing was aborted, either because the error budget was exceeded or because the context
+// passed to Start is done
+func (p *Processor) isAborted() bool {
+	return p.ctx != nil && p.ctx.Err() != nil
+}
+
+// checkMaxErrors aborts processing once more records were rejected than allowed, statsMu must be held by the caller.
+// the workers reject records concurrently, so the record kept as the first rejected one is the earliest in its input
+// rather than the first one observed
+func (p *Processor) checkMaxErrors(rejected *model.RejectedRecipe) {
+	p.rejectedCount++
+	if p.firstRejected == nil ||
+		(rejected.Source == p.firstRejected.Source && rejected.Index < p.firstRejected.Index) {
+		p.firstRejected = rejected
+	}
+
+	if p.budget.MaxErrors < 0 || p.rejectedCount <= p.budget.MaxErrors || p.budgetErr != nil {
+		return
+	}
+
+	p.budgetErr = &ErrorBudgetError{
+		Violation: ViolationMaxErrors,
+		Threshold: float64(p.budget.MaxErrors),
+	}
+	p.cancel()
+}
+
+// checkErrorRate evaluates the error budget once all the input was processed and returns the summary if it was
+// exceeded
+func (p *Processor) checkErrorRate() error {
+	dataQuality := p.GetDataQuality()
+
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	var errorRate float64
+	if dataQuality.RecordsRead > 0 {
+		errorRate = float64(p.rejectedCount) / float64(dataQuality.RecordsRead)
+	}
+
+	if p.budgetErr == nil && p.budget.MaxErrorRate >= 0 && errorRate > p.budget.MaxErrorRate {
+		p.budgetErr = &ErrorBudgetError{
+			Violation: ViolationMaxErrorRate,
+			Threshold: p.budget.MaxErrorRate,
+		}
+	}
+	if p.budgetErr == nil {
+		return nil
+	}
+
+	p.budgetErr.Rejected = p.rejectedCount
+	p.budgetErr.RecordsRead = dataQuality.RecordsRead
+	p.budgetErr.ErrorRate = errorRate
+	p.budgetErr.FirstRejected = p.firstRejected
+	p.budgetErr.DataQuality = dataQuality
+	return p.budgetErr
+}
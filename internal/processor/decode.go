@@ -0,0 +1,230 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+	"github.com/goccy/go-json"
+)
+
+type InputFormat string
+
+const (
+	InputFormatAuto   InputFormat = "auto"
+	InputFormatJSON   InputFormat = "json"
+	InputFormatNDJSON InputFormat = "ndjson"
+
+	// detectBufferSize bounds the leading whitespace that can be skipped while detecting the input format
+	detectBufferSize = 64 * 1024
+)
+
+var inputFormats = []InputFormat{InputFormatAuto, InputFormatJSON, InputFormatNDJSON}
+
+// ParseInputFormat returns the InputFormat matching the given name
+func ParseInputFormat(name string) (InputFormat, error) {
+	for _, format := range inputFormats {
+		if strings.ToLower(name) == string(format) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("invalid input format: %s, must be one of %v", name, inputFormats)
+}
+
+// decodeRecipes decodes data according to the input format of the processor and sends the records to chunkChan in
+// chunks of chunkSize as soon as a chunk fills up, so the input never has to be held in memory as a whole.
+// when the format is InputFormatAuto it is detected from the first non-whitespace byte of data. the decoded recipes
+// are tagged with name, the number of records read is returned
+func (p *Processor) decodeRecipes(name string, data io.Reader, chunkChan chan<- recordChunk) (int, error) {
+	reader := bufio.NewReaderSize(data, detectBufferSize)
+
+	format := p.inputFormat
+	if format == "" || format == InputFormatAuto {
+		var err error
+		if format, err = detectInputFormat(reader); err != nil {
+			return 0, err
+		}
+	}
+
+	chunker := newRecipeChunker(p.chunkSize, chunkChan)
+	defer chunker.flush()
+
+	if format == InputFormatNDJSON {
+		return p.decodeNDJSON(name, reader, chunker)
+	}
+	return p.decodeJSONArray(name, reader, chunker)
+}
+
+// decodeJSONArray walks the top-level JSON array of data one model.Recipe at a time
+func (p *Processor) decodeJSONArray(name string, data io.Reader, chunker *recipeChunker) (int, error) {
+	dec := json.NewDecoder(data)
+
+	if err := expectDelim(dec, '['); err != nil {
+		return 0, offsetError(dec.InputOffset(), err)
+	}
+
+	var records int
+	for dec.More() {
+		if p.isAborted() {
+			return records, ErrAborted
+		}
+
+		recipe := &model.Recipe{Source: name, Index: records}
+		if err := dec.Decode(recipe); err != nil {
+			return records, offsetError(dec.InputOffset(), err)
+		}
+		records++
+		chunker.add(recipe)
+	}
+
+	if err := expectDelim(dec, ']'); err != nil {
+		return records, offsetError(dec.InputOffset(), err)
+	}
+	return records, nil
+}
+
+// decodeNDJSON decodes data holding one JSON recipe object per line. lines that fail decoding are handed to the
+// workers alongside the decoded recipes, so that all the rejections of an input are made in the order it was read.
+// blank lines are skipped
+func (p *Processor) decodeNDJSON(name string, data *bufio.Reader, chunker *recipeChunker) (int, error) {
+	var (
+		records int
+		offset  int64
+	)
+	for lineNum := 1; ; lineNum++ {
+		if p.isAborted() {
+			return records, ErrAborted
+		}
+
+		// hand the pending recipes to the workers before waiting on the input, so that a slow stream (e.g. stdin) is
+		// aggregated as it arrives
+		if data.Buffered() == 0 {
+			chunker.flush()
+		}
+		line, err := data.ReadBytes('\n')
+		offset += int64(len(line))
+		if err != nil && err != io.EOF {
+			return records, offsetError(offset, err)
+		}
+
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			recipe := &model.Recipe{Source: name, Index: records, Line: lineNum}
+			records++
+			if decodeErr := json.Unmarshal(trimmed, recipe); decodeErr != nil {
+				chunker.addMalformed(&model.RejectedRecipe{
+					Source: name,
+					Index:  recipe.Index,
+					Line:   lineNum,
+					Raw:    string(trimmed),
+					Reason: decodeErr.Error(),
+					Code:   model.RejectionMalformedRecord,
+				})
+			} else {
+				chunker.add(recipe)
+			}
+		}
+
+		if err == io.EOF {
+			return records, nil
+		}
+	}
+}
+
+// detectInputFormat peeks at the first non-whitespace byte of data without consuming it. an array opening bracket
+// means a JSON array, an object opening brace means newline delimited JSON
+func detectInputFormat(data *bufio.Reader) (InputFormat, error) {
+	for i := 1; ; i++ {
+		peeked, err := data.Peek(i)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", fmt.Errorf("unable to detect input format: input is empty")
+			}
+			return "", fmt.Errorf("unable to detect input format: %w", err)
+		}
+
+		switch b := peeked[i-1]; b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[':
+			return InputFormatJSON, nil
+		case '{':
+			return InputFormatNDJSON, nil
+		default:
+			return "", offsetError(int64(i-1), fmt.Errorf("unable to detect input format: unexpected character %q", b))
+		}
+	}
+}
+
+// offsetError adds the byte offset in the input at which decoding failed to err
+func offsetError(offset int64, err error) error {
+	return fmt.Errorf("byte offset %d: %w", offset, err)
+}
+
+// expectDelim reads the next token from dec and fails unless it is the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %q, got %v", delim, tok)
+	}
+	return nil
+}
+
+type (
+	// recordChunk is a batch of records in the order they were read from the input
+	recordChunk []chunkRecord
+
+	// chunkRecord is either a decoded recipe or, when the record failed decoding, its rejection
+	chunkRecord struct {
+		recipe    *model.Recipe
+		malformed *model.RejectedRecipe
+	}
+)
+
+// recipeChunker batches decoded recipes into chunks of a fixed size before handing them to the workers
+type recipeChunker struct {
+	chunk     recordChunk
+	chunkSize int
+	chunkChan chan<- recordChunk
+}
+
+func newRecipeChunker(chunkSize int, chunkChan chan<- recordChunk) *recipeChunker {
+	return &recipeChunker{
+		chunk:     make(recordChunk, 0, chunkSize),
+		chunkSize: chunkSize,
+		chunkChan: chunkChan,
+	}
+}
+
+// add appends a recipe to the current chunk and sends the chunk once it is full
+func (c *recipeChunker) add(recipe *model.Recipe) {
+	c.append(chunkRecord{recipe: recipe})
+}
+
+// addMalformed appends a record that failed decoding to the current chunk, it is rejected by the worker processing
+// the chunk
+func (c *recipeChunker) addMalformed(rejected *model.RejectedRecipe) {
+	c.append(chunkRecord{malformed: rejected})
+}
+
+func (c *recipeChunker) append(record chunkRecord) {
+	c.chunk = append(c.chunk, record)
+	if len(c.chunk) >= c.chunkSize {
+		c.chunkChan <- c.chunk
+		c.chunk = make(recordChunk, 0, c.chunkSize)
+	}
+}
+
+// flush sends the remaining partial chunk (if any)
+func (c *recipeChunker) flush() {
+	if len(c.chunk) > 0 {
+		c.chunkChan <- c.chunk
+		c.chunk = make(recordChunk, 0, c.chunkSize)
+	}
+}
@@ -1,100 +1,252 @@
 package processor
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/davido912-recipe-count-test-2020/internal/aggregate"
+	"github.com/davido912-recipe-count-test-2020/internal/input"
 	"github.com/davido912-recipe-count-test-2020/internal/model"
-	"github.com/goccy/go-json"
 	"github.com/rs/zerolog/log"
 	"io"
 	"regexp"
+	"runtime"
+	"strings"
 	"sync"
 )
 
+var (
+	ErrMissingField    = errors.New("one of required fields [postcode, delivery, recipe] is missing or blank")
+	ErrInvalidDelivery = errors.New("invalid delivery time")
+	ErrIncomplete      = errors.New("processing interrupted before all the input was processed")
+
+	// ErrAborted is returned by Feed when processing was aborted before the input was fully read
+	ErrAborted = errors.New("processing aborted")
+)
+
 type Processor struct {
 	*aggregate.Aggregator
 	deliveryRegex *regexp.Regexp
 	chunkSize     int
-	dlq           chan *model.Recipe
+	inputFormat   InputFormat
+	dlq           chan *model.RejectedRecipe
+
+	// ctx is derived from the context passed to Start, it is cancelled once the error budget is exceeded
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	chunkChan     chan recordChunk
+	recipeChan    chan *model.Recipe
+	processorsWg  sync.WaitGroup
+	aggregatorsWg sync.WaitGroup
+
+	// fileStats keeps track of what every fed input contributed, keyed by the name of the input. rejections keeps
+	// track of the rejected records grouped by the reason they were rejected
+	fileStats  map[string]*model.FileStats
+	rejections map[model.RejectionReason]*model.Rejection
+	statsMu    sync.Mutex
+
+	// budget bounds the rejected records, once it is exceeded ctx is cancelled and processing stops
+	budget        ErrorBudget
+	budgetErr     *ErrorBudgetError
+	rejectedCount int
+	firstRejected *model.RejectedRecipe
 }
 
-func NewProcessor(chunkSize int, aggrinput *aggregate.AggregatorInput, dlq chan *model.Recipe) *Processor {
-	rgx, err := regexp.Compile("(?:1[0-2]|[1-9])[AP]M")
+func NewProcessor(chunkSize int, aggrinput *aggregate.AggregatorInput, dlq chan *model.RejectedRecipe) *Processor {
+	// times in 12-hour clock with optional minutes (3PM, 9:30AM) or in 24-hour clock (14:00)
+	rgx, err := regexp.Compile("(?:(?:1[0-2]|0?[1-9])(?::[0-5][0-9])?[AaPp][Mm]|(?:[01]?[0-9]|2[0-3]):[0-5][0-9])")
 	if err != nil {
 
 		panic(err)
 	}
-	return &Processor{
+	// smallest chunk size possible is 1
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+
+	p := &Processor{
 		Aggregator:    aggregate.NewAggregator(aggrinput),
 		deliveryRegex: rgx,
 		chunkSize:     chunkSize,
+		inputFormat:   InputFormatAuto,
 		dlq:           dlq,
+		fileStats:     make(map[string]*model.FileStats),
+		rejections:    newRejections(),
+		budget:        UnlimitedErrorBudget,
 	}
+	p.SetAggregatedFunc(p.accept)
+
+	return p
 }
 
-// Process main entrypoint of this component - reads the data, breaks it into chunks for faster processing.
-// if event is invalid it is discarded or forwarded to dlq channel (if present). Aggregates are finally calculated and
-// end report model is generated
-func (p *Processor) Process(data io.Reader) (*model.ReportModel, error) {
-	recipes, err := p.unmarshalRecipeData(data)
+// Process main entrypoint of this component - streams the data, batches it into chunks and fans the chunks out to a
+// fixed pool of workers for faster processing. Memory usage is bound by the chunk size rather than by the size of the
+// input. if event is invalid it is discarded or forwarded to dlq channel (if present). Aggregates are finally
+// calculated and end report model is generated. processing stops early once ctx is done, in which case the partial
+// report is returned alongside ErrIncomplete, as Finish does
+func (p *Processor) Process(ctx context.Context, data io.Reader) (*model.ReportModel, error) {
+	p.Start(ctx)
+	feedErr := p.Feed("", data)
+	report, err := p.Finish()
+
+	if errors.Is(err, ErrIncomplete) {
+		return report, err
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed parsing JSON input file: %w", err)
+		return nil, err
+	}
+	if feedErr != nil {
+		return nil, fmt.Errorf("failed parsing input file: %w", feedErr)
 	}
 
-	chunks := toChunks(recipes, p.chunkSize)
-	log.Debug().Msgf("chunk size of %d generated %d chunks", p.chunkSize, len(chunks))
+	return report, nil
+}
+
+// Start spins up the workers and the aggregator, after which any number of inputs can be passed to Feed (also
+// concurrently). Finish must be called once all the inputs were fed. once ctx is done the workers and the aggregator
+// stop and the inputs still being fed are abandoned
+func (p *Processor) Start(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
 
-	var processorsWg sync.WaitGroup
-	processorsWg.Add(len(chunks))
+	workers := runtime.NumCPU()
+	log.Debug().Msgf("chunk size of %d fanned out to %d workers", p.chunkSize, workers)
+
+	// chunkChan carries decoded chunks to the workers while the decoder keeps reading the input
+	p.chunkChan = make(chan recordChunk, workers)
 
 	// recipeChan is used to allow concurrent aggregating while processing is still taking place
 	// buffered channel is used to not block
-	recipeChan := make(chan *model.Recipe, len(recipes))
-
-	for _, chunk := range chunks {
-
-		go func(recipes model.Recipes) {
-			defer processorsWg.Done()
-			for _, recipe := range recipes {
-				err := p.processRecipe(recipe)
-				if err != nil {
-					log.Error().Err(err).Msgf("failed processing recipe: %T", recipe)
-					if p.dlq != nil {
-						p.dlq <- recipe
-					}
+	p.recipeChan = make(chan *model.Recipe, workers*p.chunkSize)
+
+	p.processorsWg.Add(workers)
+
+	for i := 0; i < workers; i++ {
 
-				} else {
-					recipeChan <- recipe
+		go func() {
+			defer p.processorsWg.Done()
+			for records := range p.chunkChan {
+				// keep draining the chunks once aborted so that the decoders are not blocked
+				if p.isAborted() {
+					continue
+				}
+				for _, record := range records {
+					if record.malformed != nil {
+						p.reject(record.malformed)
+						continue
+					}
+					recipe := record.recipe
+					err := p.processRecipe(recipe)
+					if err != nil {
+						p.reject(&model.RejectedRecipe{
+							Source: recipe.Source,
+							Index:  recipe.Index,
+							Line:   recipe.Line,
+							Recipe: recipe,
+							Reason: err.Error(),
+							Code:   rejectionReason(err),
+						})
+						continue
+					}
+					// the aggregator stops consuming once ctx is done, the recipe is dropped rather than blocking
+					select {
+					case p.recipeChan <- recipe:
+					case <-p.ctx.Done():
+					}
 				}
 			}
-		}(chunk)
+		}()
 
 	}
 
-	var aggregatorsWg sync.WaitGroup
-	aggregatorsWg.Add(1)
+	p.aggregatorsWg.Add(1)
 
 	go func() {
-		defer aggregatorsWg.Done()
-		p.Aggregate(recipeChan)
+		defer p.aggregatorsWg.Done()
+		p.Aggregate(p.ctx, p.recipeChan)
 	}()
+}
+
+// Feed decodes data and hands its recipes to the workers started by Start. name identifies the input in the per
+// file statistics. records that were decoded before an error occurred are still aggregated. Feed returns ErrAborted
+// as soon as processing is aborted, also when it is blocked reading data
+func (p *Processor) Feed(name string, data io.Reader) error {
+	records, err := p.decodeRecipes(name, input.NewContextReader(p.ctx, data), p.chunkChan)
+	if err != nil && p.isAborted() && errors.Is(err, p.ctx.Err()) {
+		err = ErrAborted
+	}
+
+	p.statsMu.Lock()
+	p.getFileStats(name).Records += records
+	p.statsMu.Unlock()
+
+	return err
+}
+
+// Finish waits for all the fed recipes to be processed and aggregated and generates the end report model.
+// an ErrorBudgetError is returned when more records were rejected than the error budget allows. when the context
+// passed to Start was done before all the recipes were aggregated, ErrIncomplete is returned alongside the partial
+// report, which is marked as incomplete
+func (p *Processor) Finish() (*model.ReportModel, error) {
+	defer p.cancel()
 
 	// wait for processors to finish transmitting all events to recipe channel
-	processorsWg.Wait()
-	close(recipeChan)
-	aggregatorsWg.Wait()
+	close(p.chunkChan)
+	p.processorsWg.Wait()
+	close(p.recipeChan)
+	p.aggregatorsWg.Wait()
+
+	if err := p.checkErrorRate(); err != nil {
+		return p.generateReport(), err
+	}
+
+	if err := p.ctx.Err(); err != nil {
+		report := p.generateReport()
+		report.SetIncomplete(true)
+		return report, fmt.Errorf("%w: %s", ErrIncomplete, err)
+	}
 
 	return p.generateReport(), nil
 }
 
+// SetInputFormat sets the format the input is decoded with, by default the format is detected from the input
+func (p *Processor) SetInputFormat(format InputFormat) {
+	p.inputFormat = format
+}
+
+// reject discards an invalid event or forwards it to dlq channel (if present). forwarding is given up once processing
+// is done, so a dlq consumer that stopped reading cannot block the workers forever
+func (p *Processor) reject(rejected *model.RejectedRecipe) {
+	log.Error().Str("source", rejected.Source).Int("index", rejected.Index).Int("line", rejected.Line).
+		Msgf("failed processing recipe: %s", rejected.Reason)
+
+	p.statsMu.Lock()
+	p.getFileStats(rejected.Source).Rejected++
+	p.recordRejection(rejected)
+	p.checkMaxErrors(rejected)
+	p.statsMu.Unlock()
+
+	if p.dlq != nil {
+		select {
+		case p.dlq <- rejected:
+		case <-p.ctx.Done():
+		}
+	}
+}
+
+// accept counts a recipe that was aggregated
+func (p *Processor) accept(recipe *model.Recipe) {
+	p.statsMu.Lock()
+	p.getFileStats(recipe.Source).Accepted++
+	p.statsMu.Unlock()
+}
+
 // generateReport outputs the final model used for the reporting
 func (p *Processor) generateReport() *model.ReportModel {
 	reportModel := model.NewReportModel()
-	reportModel.SetUniqueRecipeCount(p.GetUniqueRecipeCount())
-	reportModel.SetCountPerRecipe(p.GetRecipeCountsModel())
-	reportModel.SetMatchByName(p.GetRecipeMatches())
-	reportModel.SetCountPerPostcodeAndTime(p.GetPostcodeTimeCount())
-	reportModel.SetBusiestPostcode(p.GetBusiestPostcode())
+	p.Contribute(reportModel)
+	reportModel.SetRejectedCount(p.GetRejectedCount())
+	reportModel.SetDataQuality(p.GetDataQuality())
 	return reportModel
 }
 
@@ -104,78 +256,51 @@ func (p *Processor) processRecipe(recipe *model.Recipe) error {
 		return err
 	}
 
-	err := p.parseDelivery(recipe)
-	if err != nil {
+	if err := aggregate.ValidateRecipe(recipe); err != nil {
 		return err
 	}
 
-	return nil
-}
-
-// unmarshalRecipeData read data from a buffer/file and deserialize into []model.Recipe
-func (p *Processor) unmarshalRecipeData(data io.Reader) (model.Recipes, error) {
-	bs, _ := io.ReadAll(data)
-
-	var recipes model.Recipes
-
-	err := json.Unmarshal(bs, &recipes)
+	err := p.parseDelivery(recipe)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return recipes, nil
+	return nil
 }
 
 // validateRequiredFields ensures all the fields are present in the JSON events
 func (p *Processor) validateRequiredFields(recipe *model.Recipe) error {
 	if recipe.Recipe == "" || recipe.Delivery == "" || recipe.Postcode == "" {
-		return fmt.Errorf("one of required fields [postcode, delivery, recipe] is missing or blank")
+		return ErrMissingField
 	}
 	return nil
 }
 
-// parseDelivery parses the delivery field in the JSON events into a deserialized object
+// parseDelivery parses the delivery field in the JSON events ("{weekday} {h}AM - {h}PM", also with minutes as in
+// "{weekday} {h}:{mm}AM - {h}:{mm}PM" or in 24-hour clock as in "{weekday} {hh}:{mm} - {hh}:{mm}") into a deserialized
+// object
 func (p *Processor) parseDelivery(recipe *model.Recipe) error {
 	found := p.deliveryRegex.FindAll([]byte(recipe.Delivery), -1)
 
 	if len(found) < 2 {
-		return fmt.Errorf("invalid delivery time: %s", recipe.Delivery)
+		return fmt.Errorf("%w: %s", ErrInvalidDelivery, recipe.Delivery)
+	}
+
+	weekday, err := model.ParseWeekday(strings.Fields(recipe.Delivery)[0])
+	if err != nil {
+		return fmt.Errorf("%w: %s: %s", ErrInvalidDelivery, recipe.Delivery, err)
 	}
 
 	from, to := string(found[0]), string(found[1])
 
 	parsedFrom, err := model.NewDeliveryTime(from)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %s: %s", ErrInvalidDelivery, recipe.Delivery, err)
 	}
 	parsedTo, err := model.NewDeliveryTime(to)
 	if err != nil {
-		return err
+		return fmt.Errorf("%w: %s: %s", ErrInvalidDelivery, recipe.Delivery, err)
 	}
-	recipe.From, recipe.To = parsedFrom, parsedTo
+	recipe.From, recipe.To, recipe.Weekday = parsedFrom, parsedTo, weekday
 	return nil
 }
-
-// toChunks helper function to chunk big arrays resulted from JSON unmarshalling to allow for concurrency and faster
-// processing. Smallest chunkSize possible is 1
-func toChunks[T any](slice []T, chunkSize int) [][]T {
-	if chunkSize < 1 {
-		chunkSize = 1
-	}
-
-	var chunks [][]T
-	for {
-		if len(slice) == 0 {
-			break
-		}
-
-		if len(slice) < chunkSize {
-			chunkSize = len(slice)
-		}
-
-		chunks = append(chunks, slice[:chunkSize])
-		slice = slice[chunkSize:]
-	}
-
-	return chunks
-}
@@ -1,15 +1,36 @@
 package processor
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"github.com/davido912-recipe-count-test-2020/internal/aggregate"
 	"github.com/davido912-recipe-count-test-2020/internal/model"
 	"github.com/davido912-recipe-count-test-2020/internal/testutils"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
+// mockDataQuality returns the data quality model with the given rejections, all the other reasons have no rejections
+func mockDataQuality(read, accepted int, rejections ...model.Rejection) model.DataQuality {
+	dataQuality := model.DataQuality{RecordsRead: read, RecordsAccepted: accepted}
+	for _, reason := range model.RejectionReasons {
+		rejection := model.Rejection{Reason: reason, Samples: []string{}}
+		for _, r := range rejections {
+			if r.Reason == reason {
+				rejection = r
+			}
+		}
+		dataQuality.Rejections = append(dataQuality.Rejections, rejection)
+	}
+	return dataQuality
+}
+
 func TestProcessor_Process(t *testing.T) {
 	tcs := []struct {
 		name    string
@@ -30,13 +51,42 @@ func TestProcessor_Process(t *testing.T) {
 					Postcode:      "10311",
 					DeliveryCount: 1,
 				},
-				CountPerPostcodeAndTime: model.PostcodeTimeCount{
-					Postcode:      "10245",
-					From:          "10AM",
-					To:            "3PM",
-					DeliveryCount: 0,
+				CountPerPostcodeAndTime: []model.PostcodeTimeCount{
+					{Postcode: "10245", From: "10AM", To: "3PM", DeliveryCount: 0},
+				},
+				MatchByName:    model.RecipeMatches{},
+				MatchByTerm:    []model.TermMatch{{Term: "ea", Recipes: []model.MatchedRecipe{}}},
+				UnmatchedTerms: []string{"ea"},
+				RejectedCount:  1,
+				DataQuality: mockDataQuality(2, 1, model.Rejection{
+					Reason: model.RejectionMissingField, Count: 1, Samples: []string{"postcode"},
+				}),
+			},
+			wantErr: false,
+		},
+		{
+			name: "ndjson with invalid line",
+			data: bytes.NewBufferString(`{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+											{"postcode": "10311","recipe": "Hon`),
+			want: &model.ReportModel{
+				UniqueRecipeCount: 1,
+				CountPerRecipe: model.RecipeCounts{
+					{Recipe: "Honey", RecipeCount: 1},
+				},
+				BusiestPostcode: model.PostcodeCount{
+					Postcode:      "10311",
+					DeliveryCount: 1,
+				},
+				CountPerPostcodeAndTime: []model.PostcodeTimeCount{
+					{Postcode: "10245", From: "10AM", To: "3PM", DeliveryCount: 0},
 				},
-				MatchByName: model.RecipeMatches{},
+				MatchByName:    model.RecipeMatches{},
+				MatchByTerm:    []model.TermMatch{{Term: "ea", Recipes: []model.MatchedRecipe{}}},
+				UnmatchedTerms: []string{"ea"},
+				RejectedCount:  1,
+				DataQuality: mockDataQuality(2, 1, model.Rejection{
+					Reason: model.RejectionMalformedRecord, Count: 1, Samples: []string{`{"postcode": "10311","recipe": "Hon`},
+				}),
 			},
 			wantErr: false,
 		},
@@ -56,13 +106,19 @@ func TestProcessor_Process(t *testing.T) {
 					Postcode:      "10245",
 					DeliveryCount: 3,
 				},
-				CountPerPostcodeAndTime: model.PostcodeTimeCount{
-					Postcode:      "10245",
-					From:          "10AM",
-					To:            "3PM",
-					DeliveryCount: 2,
+				CountPerPostcodeAndTime: []model.PostcodeTimeCount{
+					{Postcode: "10245", From: "10AM", To: "3PM", DeliveryCount: 2},
 				},
 				MatchByName: model.RecipeMatches{"Pear", "Steak"},
+				MatchByTerm: []model.TermMatch{
+					{
+						Term:          "ea",
+						DeliveryCount: 2,
+						Recipes:       []model.MatchedRecipe{{Recipe: "Pear", RecipeCount: 1}, {Recipe: "Steak", RecipeCount: 1}},
+					},
+				},
+				UnmatchedTerms: []string{},
+				DataQuality:    mockDataQuality(6, 6),
 			},
 			wantErr: false,
 		},
@@ -72,14 +128,18 @@ func TestProcessor_Process(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 
 			aggrInput := &aggregate.AggregatorInput{
-				Postcode:     "10245",
-				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
-				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
-				Terms:        []string{"ea"},
+				Queries: []aggregate.PostcodeQuery{
+					{
+						Postcode:     "10245",
+						DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+						DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+					},
+				},
+				Terms: []string{"ea"},
 			}
 			p := NewProcessor(1, aggrInput, nil)
 
-			got, err := p.Process(tc.data)
+			got, err := p.Process(context.Background(), tc.data)
 
 			if tc.wantErr {
 				assert.NotNil(t, err)
@@ -93,17 +153,338 @@ func TestProcessor_Process(t *testing.T) {
 	}
 }
 
-func TestProcessor_unmarshalRecipeData(t *testing.T) {
+func TestProcessor_ProcessDLQ(t *testing.T) {
+	aggrInput := &aggregate.AggregatorInput{
+		Queries: []aggregate.PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+			},
+		},
+	}
+	dlq := make(chan *model.RejectedRecipe, 10)
+	p := NewProcessor(1, aggrInput, dlq)
+
+	_, err := p.Process(context.Background(), bytes.NewBufferString(`[{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"},
+		{"recipe": "Steak","delivery": "Thursday 3PM - 4PM"},
+		{"postcode": "10311","recipe": "Salt","delivery": "Thursday 3PM"}]`))
+	close(dlq)
+	assert.Nil(t, err)
+
+	var got []*model.RejectedRecipe
+	for rejected := range dlq {
+		got = append(got, rejected)
+	}
+	sort.Slice(got, func(i, j int) bool {
+		return got[i].Index < got[j].Index
+	})
+
+	want := []*model.RejectedRecipe{
+		{
+			Index:  1,
+			Recipe: &model.Recipe{Recipe: "Steak", Delivery: "Thursday 3PM - 4PM", Index: 1},
+			Reason: "one of required fields [postcode, delivery, recipe] is missing or blank",
+			Code:   model.RejectionMissingField,
+		},
+		{
+			Index:  2,
+			Recipe: &model.Recipe{Postcode: "10311", Recipe: "Salt", Delivery: "Thursday 3PM", Index: 2},
+			Reason: "invalid delivery time: Thursday 3PM",
+			Code:   model.RejectionInvalidDelivery,
+		},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestProcessor_Feed(t *testing.T) {
+	aggrInput := &aggregate.AggregatorInput{
+		Queries: []aggregate.PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+			},
+		},
+		Terms: []string{"ea"},
+	}
+	p := NewProcessor(1, aggrInput, nil)
+
+	p.Start(context.Background())
+	assert.Nil(t, p.Feed("a.json", testutils.MockData()))
+	assert.Nil(t, p.Feed("b.json", bytes.NewBufferString(`{"postcode": "10342","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+	{"postcode": "10311","recipe": "Honey","delivery": "Thursday"}`)))
+	assert.NotNil(t, p.Feed("c.json", bytes.NewBufferString(`fff`)))
+	got, err := p.Finish()
+	assert.Nil(t, err)
+
+	assert.Equal(t, 5, got.UniqueRecipeCount)
+	assert.Contains(t, got.CountPerRecipe, model.RecipeCount{Recipe: "Honey", RecipeCount: 3})
+	assert.Equal(t, model.PostcodeCount{Postcode: "10245", DeliveryCount: 3}, got.BusiestPostcode)
+
+	wantFileStats := []model.FileStats{
+		{File: "a.json", Records: 6, Accepted: 6, Rejected: 0},
+		{File: "b.json", Records: 2, Accepted: 1, Rejected: 1},
+		{File: "c.json", Records: 0, Accepted: 0, Rejected: 0},
+	}
+	assert.Equal(t, wantFileStats, p.GetFileStats())
+}
+
+func TestProcessor_ErrorBudget(t *testing.T) {
+	data := `{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+{"recipe": "Steak","delivery": "Thursday 3PM - 4PM"}
+{"postcode": "10311","recipe": "Salt","delivery": "Thursday 3PM - 5PM"}
+{"postcode": "10311","recipe": "Salt","delivery": "Thursday 3PM"}`
+
 	tcs := []struct {
-		name    string
-		data    io.Reader
-		want    model.Recipes
-		wantErr bool
+		name          string
+		budget        ErrorBudget
+		wantViolation string
+		wantRejected  int
+		wantErr       bool
 	}{
 		{
-			name:    "happy path",
-			data:    bytes.NewBufferString(`[{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}]`),
-			want:    model.Recipes{&model.Recipe{Postcode: "10311", Recipe: "Honey", Delivery: "Thursday 3PM - 4PM"}},
+			name:    "unlimited budget",
+			budget:  UnlimitedErrorBudget,
+			wantErr: false,
+		},
+		{
+			name:          "strict",
+			budget:        ErrorBudget{MaxErrors: 0, MaxErrorRate: -1},
+			wantViolation: ViolationMaxErrors,
+			wantRejected:  1,
+			wantErr:       true,
+		},
+		{
+			name:    "max errors not exceeded",
+			budget:  ErrorBudget{MaxErrors: 2, MaxErrorRate: -1},
+			wantErr: false,
+		},
+		{
+			name:    "max error rate not exceeded",
+			budget:  ErrorBudget{MaxErrors: -1, MaxErrorRate: 0.5},
+			wantErr: false,
+		},
+		{
+			name:          "max error rate exceeded",
+			budget:        ErrorBudget{MaxErrors: -1, MaxErrorRate: 0.3},
+			wantViolation: ViolationMaxErrorRate,
+			wantRejected:  2,
+			wantErr:       true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			aggrInput := &aggregate.AggregatorInput{}
+			p := NewProcessor(1, aggrInput, nil)
+			p.SetErrorBudget(tc.budget)
+
+			_, err := p.Process(context.Background(), bytes.NewBufferString(data))
+			if !tc.wantErr {
+				assert.Nil(t, err)
+				return
+			}
+
+			var budgetErr *ErrorBudgetError
+			if assert.ErrorAs(t, err, &budgetErr) {
+				assert.Equal(t, tc.wantViolation, budgetErr.Violation)
+				assert.Equal(t, tc.wantRejected, budgetErr.Rejected)
+				assert.NotNil(t, budgetErr.FirstRejected)
+			}
+		})
+	}
+}
+
+func TestProcessor_ErrorBudgetFirstRejected(t *testing.T) {
+	// the malformed line is decoded after the invalid record, it must not be reported as the first rejected record
+	data := `{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+{"recipe": "Steak","delivery": "Thursday 3PM - 4PM"}
+{"postcode": "10311","reci
+{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}`
+
+	p := NewProcessor(10, &aggregate.AggregatorInput{}, nil)
+	p.SetErrorBudget(ErrorBudget{MaxErrors: 0, MaxErrorRate: -1})
+
+	_, err := p.Process(context.Background(), bytes.NewBufferString(data))
+
+	var budgetErr *ErrorBudgetError
+	if assert.ErrorAs(t, err, &budgetErr) && assert.NotNil(t, budgetErr.FirstRejected) {
+		assert.Equal(t, 4, budgetErr.FirstRejected.Line)
+		assert.Equal(t, model.RejectionMissingField, budgetErr.FirstRejected.Code)
+	}
+}
+
+func TestProcessor_checkMaxErrorsEarliest(t *testing.T) {
+	p := NewProcessor(1, &aggregate.AggregatorInput{}, nil)
+	p.Start(context.Background())
+
+	p.checkMaxErrors(&model.RejectedRecipe{Source: "a.json", Index: 5})
+	p.checkMaxErrors(&model.RejectedRecipe{Source: "a.json", Index: 3})
+	p.checkMaxErrors(&model.RejectedRecipe{Source: "b.json", Index: 1})
+	_, _ = p.Finish()
+
+	assert.Equal(t, &model.RejectedRecipe{Source: "a.json", Index: 3}, p.firstRejected)
+}
+
+func TestProcessor_Cancelled(t *testing.T) {
+	aggrInput := &aggregate.AggregatorInput{}
+	p := NewProcessor(1, aggrInput, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p.Start(ctx)
+	assert.ErrorIs(t, p.Feed("a.json", testutils.MockData()), ErrAborted)
+	got, err := p.Finish()
+
+	assert.ErrorIs(t, err, ErrIncomplete)
+	assert.True(t, got.Incomplete)
+}
+
+func TestProcessor_FeedBlockedReader(t *testing.T) {
+	aggrInput := &aggregate.AggregatorInput{}
+	// the record read so far is aggregated although its chunk is not full
+	p := NewProcessor(10, aggrInput, nil)
+
+	// a single record is read, then the reader blocks forever as stdin does when nothing is written to it
+	blocked, _ := io.Pipe()
+	data := io.MultiReader(
+		strings.NewReader(`{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}`+"\n"),
+		blocked,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	p.Start(ctx)
+	start := time.Now()
+	assert.ErrorIs(t, p.Feed("-", data), ErrAborted)
+	got, err := p.Finish()
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, err, ErrIncomplete)
+	assert.True(t, got.Incomplete)
+	assert.Equal(t, 1, got.UniqueRecipeCount)
+}
+
+func TestProcessor_ProcessBlockedDLQ(t *testing.T) {
+	aggrInput := &aggregate.AggregatorInput{}
+	// nobody reads the dlq, the timeout must unblock the workers
+	dlq := make(chan *model.RejectedRecipe)
+	p := NewProcessor(1, aggrInput, dlq)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	got, err := p.Process(ctx, bytes.NewBufferString(`[{"recipe": "Steak","delivery": "Thursday 3PM - 4PM"}]`))
+	assert.ErrorIs(t, err, ErrIncomplete)
+	if assert.NotNil(t, got) {
+		assert.True(t, got.Incomplete)
+	}
+}
+
+// slowMetric is a custom metric taking its time to aggregate every recipe
+type slowMetric struct{}
+
+func (m slowMetric) Name() string {
+	return "slow_metric"
+}
+
+func (m slowMetric) Aggregate(_ *model.Recipe) {
+	time.Sleep(10 * time.Millisecond)
+}
+
+func (m slowMetric) Finalize() {}
+
+func (m slowMetric) Contribute(_ *model.ReportModel) {}
+
+func init() {
+	aggregate.Register(slowMetric{}.Name(), func(_ *aggregate.AggregatorInput) aggregate.Metric {
+		return slowMetric{}
+	})
+}
+
+func TestProcessor_ProcessSlowAggregator(t *testing.T) {
+	aggrInput := &aggregate.AggregatorInput{Metrics: []string{slowMetric{}.Name()}}
+	// the workers are still sending the recipes of their chunks when the deadline passes
+	p := NewProcessor(10, aggrInput, nil)
+
+	var data strings.Builder
+	for i := 0; i < 1000; i++ {
+		data.WriteString(`{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}` + "\n")
+	}
+
+	// the aggregator stops consuming once the deadline passes, the workers must not block on the recipe channel
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		_, err := p.Process(ctx, strings.NewReader(data.String()))
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrIncomplete)
+	case <-time.After(5 * time.Second):
+		t.Fatal("processing did not stop after the deadline")
+	}
+}
+
+func TestProcessor_AcceptedAfterDeadline(t *testing.T) {
+	aggrInput := &aggregate.AggregatorInput{Metrics: []string{slowMetric{}.Name(), model.SectionCountPerRecipe}}
+	p := NewProcessor(10, aggrInput, nil)
+
+	var data strings.Builder
+	for i := 0; i < 1000; i++ {
+		data.WriteString(`{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}` + "\n")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	p.Start(ctx)
+	assert.ErrorIs(t, p.Feed("a.json", strings.NewReader(data.String())), ErrAborted)
+	got, err := p.Finish()
+	assert.ErrorIs(t, err, ErrIncomplete)
+
+	// the records that were read but never aggregated, also the ones left in the recipe channel, are not accepted
+	var aggregated int
+	for _, recipeCount := range got.CountPerRecipe {
+		aggregated += recipeCount.RecipeCount
+	}
+	stats := p.GetFileStats()[0]
+	assert.Zero(t, stats.Rejected)
+	assert.Less(t, stats.Accepted, stats.Records)
+	assert.Equal(t, aggregated, stats.Accepted)
+	assert.Equal(t, aggregated, got.DataQuality.RecordsAccepted)
+}
+
+func TestProcessor_decodeRecipes(t *testing.T) {
+	tcs := []struct {
+		name         string
+		data         io.Reader
+		format       InputFormat
+		want         model.Recipes
+		wantRecords  int
+		wantRejected []*model.RejectedRecipe
+		wantErr      bool
+	}{
+		{
+			name:        "happy path",
+			data:        bytes.NewBufferString(`[{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}]`),
+			want:        model.Recipes{&model.Recipe{Postcode: "10311", Recipe: "Honey", Delivery: "Thursday 3PM - 4PM"}},
+			wantRecords: 1,
+			wantErr:     false,
+		},
+		{
+			name:    "empty array",
+			data:    bytes.NewBufferString(`[]`),
+			want:    nil,
 			wantErr: false,
 		},
 		{
@@ -112,24 +493,232 @@ func TestProcessor_unmarshalRecipeData(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name:    "top level object instead of array",
+			data:    bytes.NewBufferString(`{"postcode": "10311"}`),
+			format:  InputFormatJSON,
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:        "truncated array",
+			data:        bytes.NewBufferString(`[{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}`),
+			want:        model.Recipes{&model.Recipe{Postcode: "10311", Recipe: "Honey", Delivery: "Thursday 3PM - 4PM"}},
+			wantRecords: 1,
+			wantErr:     true,
+		},
+		{
+			name: "ndjson detected",
+			data: bytes.NewBufferString("\n {\"postcode\": \"10311\",\"recipe\": \"Honey\"}\n\n" +
+				"{\"postcode\": \"10245\",\"recipe\": \"Steak\"}"),
+			want: model.Recipes{
+				&model.Recipe{Postcode: "10311", Recipe: "Honey", Line: 2},
+				&model.Recipe{Postcode: "10245", Recipe: "Steak", Index: 1, Line: 4},
+			},
+			wantRecords: 2,
+			wantErr:     false,
+		},
+		{
+			name: "ndjson invalid line rejected",
+			data: bytes.NewBufferString("{\"postcode\": \"10311\",\"recipe\": \"Honey\"}\n" +
+				"{\"postcode\": \"10245\",\"reci\n"),
+			want:        model.Recipes{&model.Recipe{Postcode: "10311", Recipe: "Honey", Line: 1}},
+			wantRecords: 2,
+			wantRejected: []*model.RejectedRecipe{
+				{Index: 1, Line: 2, Raw: `{"postcode": "10245","reci`, Code: model.RejectionMalformedRecord},
+			},
+			wantErr: false,
+		},
+		{
+			name:        "ndjson forced on array input",
+			data:        bytes.NewBufferString(`[{"postcode": "10311","recipe": "Honey"}]`),
+			format:      InputFormatNDJSON,
+			want:        nil,
+			wantRecords: 1,
+			wantRejected: []*model.RejectedRecipe{
+				{Line: 1, Raw: `[{"postcode": "10311","recipe": "Honey"}]`, Code: model.RejectionMalformedRecord},
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 
-			p := Processor{}
-			gotRecipes, err := p.unmarshalRecipeData(tc.data)
+			p := Processor{
+				chunkSize:   1,
+				inputFormat: tc.format,
+				fileStats:   make(map[string]*model.FileStats),
+				rejections:  newRejections(),
+				budget:      UnlimitedErrorBudget,
+				ctx:         context.Background(),
+			}
+			chunkChan := make(chan recordChunk, 10)
+			records, err := p.decodeRecipes("", tc.data, chunkChan)
+			close(chunkChan)
+
+			assert.Equal(t, tc.wantRecords, records)
+
 			if tc.wantErr {
 				assert.NotNil(t, err)
 			} else {
 				assert.Nil(t, err)
 			}
+
+			var (
+				gotRecipes  model.Recipes
+				gotRejected []*model.RejectedRecipe
+			)
+			for chunk := range chunkChan {
+				for _, record := range chunk {
+					if record.malformed == nil {
+						gotRecipes = append(gotRecipes, record.recipe)
+						continue
+					}
+					assert.NotEmpty(t, record.malformed.Reason)
+					record.malformed.Reason = ""
+					gotRejected = append(gotRejected, record.malformed)
+				}
+			}
 			assert.Equal(t, tc.want, gotRecipes)
+			assert.Equal(t, tc.wantRejected, gotRejected)
+
+		})
+	}
+}
+
+func TestProcessor_decodeRecipesErrorOffset(t *testing.T) {
+	tcs := []struct {
+		name       string
+		data       string
+		wantOffset string
+	}{
+		{
+			name:       "undetectable input",
+			data:       "  fff",
+			wantOffset: "byte offset 2:",
+		},
+		{
+			name:       "truncated array",
+			data:       `[{"recipe": "Honey"},{"reci`,
+			wantOffset: "byte offset 27:",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Processor{chunkSize: 1, fileStats: make(map[string]*model.FileStats), rejections: newRejections(),
+				budget: UnlimitedErrorBudget, ctx: context.Background()}
+			chunkChan := make(chan recordChunk, 10)
+			_, err := p.decodeRecipes("", bytes.NewBufferString(tc.data), chunkChan)
 
+			assert.NotNil(t, err)
+			assert.Contains(t, err.Error(), tc.wantOffset)
 		})
 	}
 }
 
+func Test_detectInputFormat(t *testing.T) {
+	tcs := []struct {
+		name    string
+		data    string
+		want    InputFormat
+		wantErr bool
+	}{
+		{
+			name: "json array",
+			data: ` [{"recipe": "Honey"}]`,
+			want: InputFormatJSON,
+		},
+		{
+			name: "ndjson",
+			data: "\n\t{\"recipe\": \"Honey\"}\n",
+			want: InputFormatNDJSON,
+		},
+		{
+			name:    "empty input",
+			data:    "  \n",
+			wantErr: true,
+		},
+		{
+			name:    "not json",
+			data:    "recipe,postcode",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+
+			got, err := detectInputFormat(bufio.NewReader(bytes.NewBufferString(tc.data)))
+			if tc.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tc.want, got)
+
+		})
+	}
+}
+
+func TestProcessor_GetDataQuality(t *testing.T) {
+	aggrInput := &aggregate.AggregatorInput{
+		Queries: []aggregate.PostcodeQuery{
+			{
+				Postcode:     "10245",
+				DeliveryFrom: testutils.MockDeliveryTime("10AM"),
+				DeliveryTo:   testutils.MockDeliveryTime("3PM"),
+			},
+		},
+	}
+	p := NewProcessor(2, aggrInput, nil)
+
+	longName := strings.Repeat("Honey", 21)
+	_, err := p.Process(context.Background(), bytes.NewBufferString(`
+		{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+		{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+		{"postcode": "10311","recipe": "Honey"}
+		{"recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+		{"recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+		{"postcode": "10311","recipe": "Honey","delivery": "Thursday 3PM"}
+		{"postcode": "10311","recipe": "Honey","delivery": "Thursday noon"}
+		{"postcode": "10311","recipe": "Honey","delivery": "Thursday noon"}
+		{"postcode": "10311","recipe": "Honey","delivery": "Friday noon"}
+		{"postcode": "10311123456","recipe": "Honey","delivery": "Thursday 3PM - 4PM"}
+		{"postcode": "10311","recipe": "`+longName+`","delivery": "Thursday 3PM - 4PM"}
+		{"postcode": 10311}`))
+	assert.Nil(t, err)
+
+	want := mockDataQuality(12, 2,
+		model.Rejection{Reason: model.RejectionMalformedRecord, Count: 1, Samples: []string{`{"postcode": 10311}`}},
+		model.Rejection{Reason: model.RejectionMissingField, Count: 3, Samples: []string{"delivery", "postcode"}},
+		model.Rejection{Reason: model.RejectionInvalidDelivery, Count: 4, Samples: []string{
+			"Friday noon", "Thursday 3PM", "Thursday noon",
+		}},
+		model.Rejection{Reason: model.RejectionPostcodeTooLong, Count: 1, Samples: []string{"10311123456"}},
+		model.Rejection{Reason: model.RejectionRecipeNameTooLong, Count: 1, Samples: []string{longName}},
+	)
+	got := p.GetDataQuality()
+	for i := range got.Rejections {
+		sort.Strings(got.Rejections[i].Samples)
+		sort.Strings(want.Rejections[i].Samples)
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestProcessor_recordRejectionLongSample(t *testing.T) {
+	p := NewProcessor(1, &aggregate.AggregatorInput{}, nil)
+
+	// multi-byte characters are not split when the sample is truncated
+	raw := strings.Repeat("é", rejectionSampleLen+5)
+	p.recordRejection(&model.RejectedRecipe{Raw: raw, Code: model.RejectionMalformedRecord})
+
+	got := p.rejections[model.RejectionMalformedRecord].Samples
+	assert.Equal(t, []string{strings.Repeat("é", rejectionSampleLen) + "..."}, got)
+	assert.True(t, utf8.ValidString(got[0]))
+}
+
 func TestProcessor_validateRequiredFields(t *testing.T) {
 	tcs := []struct {
 		name    string
@@ -175,18 +764,51 @@ func TestProcessor_validateRequiredFields(t *testing.T) {
 
 func TestProcessor_parseDelivery(t *testing.T) {
 	tcs := []struct {
-		name     string
-		recipe   *model.Recipe
-		wantErr  bool
-		wantFrom *model.DeliveryTime
-		wantTo   *model.DeliveryTime
+		name        string
+		recipe      *model.Recipe
+		wantErr     bool
+		wantFrom    *model.DeliveryTime
+		wantTo      *model.DeliveryTime
+		wantWeekday time.Weekday
 	}{
 		{
-			name:     "valid delivery",
-			recipe:   &model.Recipe{Postcode: "10311", Recipe: "Honey", Delivery: "Thursday 3PM - 4PM"},
-			wantErr:  false,
-			wantFrom: testutils.MockDeliveryTime("3PM"),
-			wantTo:   testutils.MockDeliveryTime("4PM"),
+			name:        "valid delivery",
+			recipe:      &model.Recipe{Postcode: "10311", Recipe: "Honey", Delivery: "Thursday 3PM - 4PM"},
+			wantErr:     false,
+			wantFrom:    testutils.MockDeliveryTime("3PM"),
+			wantTo:      testutils.MockDeliveryTime("4PM"),
+			wantWeekday: time.Thursday,
+		},
+		{
+			name:        "overnight delivery",
+			recipe:      &model.Recipe{Postcode: "10311", Recipe: "Honey", Delivery: "Friday 10PM - 2AM"},
+			wantErr:     false,
+			wantFrom:    testutils.MockDeliveryTime("10PM"),
+			wantTo:      testutils.MockDeliveryTime("2AM"),
+			wantWeekday: time.Friday,
+		},
+		{
+			name:        "delivery with minutes",
+			recipe:      &model.Recipe{Postcode: "10311", Recipe: "Honey", Delivery: "Monday 9:30AM - 1:15PM"},
+			wantErr:     false,
+			wantFrom:    testutils.MockDeliveryTime("9:30AM"),
+			wantTo:      testutils.MockDeliveryTime("1:15PM"),
+			wantWeekday: time.Monday,
+		},
+		{
+			name:        "delivery in 24-hour clock",
+			recipe:      &model.Recipe{Postcode: "10311", Recipe: "Honey", Delivery: "Monday 14:00 - 18:00"},
+			wantErr:     false,
+			wantFrom:    testutils.MockDeliveryTime("14:00"),
+			wantTo:      testutils.MockDeliveryTime("18:00"),
+			wantWeekday: time.Monday,
+		},
+		{
+			name:     "invalid weekday",
+			recipe:   &model.Recipe{Postcode: "10311", Recipe: "Honey", Delivery: "Someday 3PM - 4PM"},
+			wantErr:  true,
+			wantFrom: nil,
+			wantTo:   nil,
 		},
 		{
 			name:     "invalid delivery",
@@ -208,10 +830,14 @@ func TestProcessor_parseDelivery(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 
 			aggrInput := &aggregate.AggregatorInput{
-				Postcode:     "10311",
-				DeliveryFrom: testutils.MockDeliveryTime("8PM"),
-				DeliveryTo:   testutils.MockDeliveryTime("11PM"),
-				Terms:        []string{},
+				Queries: []aggregate.PostcodeQuery{
+					{
+						Postcode:     "10311",
+						DeliveryFrom: testutils.MockDeliveryTime("8PM"),
+						DeliveryTo:   testutils.MockDeliveryTime("11PM"),
+					},
+				},
+				Terms: []string{},
 			}
 			p := NewProcessor(0, aggrInput, nil)
 			err := p.parseDelivery(tc.recipe)
@@ -223,38 +849,39 @@ func TestProcessor_parseDelivery(t *testing.T) {
 
 			assert.Equal(t, tc.wantFrom, tc.recipe.From)
 			assert.Equal(t, tc.wantTo, tc.recipe.To)
+			assert.Equal(t, tc.wantWeekday, tc.recipe.Weekday)
 		})
 	}
 }
 
-func Test_toChunks(t *testing.T) {
+func TestProcessor_decodeRecipesChunks(t *testing.T) {
 	tcs := []struct {
 		name       string
-		slice      []string
+		records    int
 		chunksize  int
 		wantChunks int
 	}{
 		{
 			name:       "test chunks when even chunk size",
-			slice:      make([]string, 100),
+			records:    100,
 			chunksize:  20,
 			wantChunks: 5,
 		},
 		{
 			name:       "test chunks when even uneven chunk size",
-			slice:      make([]string, 100),
+			records:    100,
 			chunksize:  30,
 			wantChunks: 4,
 		},
 		{
-			name:       "test chunks when chunk size bigger than slice size",
-			slice:      make([]string, 100),
+			name:       "test chunks when chunk size bigger than input size",
+			records:    100,
 			chunksize:  200,
 			wantChunks: 1,
 		},
 		{
 			name:       "zero chunk size - defaults to 1",
-			slice:      make([]string, 100),
+			records:    100,
 			chunksize:  0,
 			wantChunks: 100,
 		},
@@ -263,8 +890,16 @@ func Test_toChunks(t *testing.T) {
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 
-			chunks := toChunks(tc.slice, tc.chunksize)
-			assert.Equal(t, tc.wantChunks, len(chunks))
+			data := "[" + strings.TrimSuffix(strings.Repeat(`{"recipe": "Honey"},`, tc.records), ",") + "]"
+			aggrInput := &aggregate.AggregatorInput{}
+			p := NewProcessor(tc.chunksize, aggrInput, nil)
+			chunkChan := make(chan recordChunk, tc.records)
+
+			_, err := p.decodeRecipes("", bytes.NewBufferString(data), chunkChan)
+			close(chunkChan)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantChunks, len(chunkChan))
 
 		})
 	}
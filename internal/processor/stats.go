@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/davido912-recipe-count-test-2020/internal/aggregate"
+	"github.com/davido912-recipe-count-test-2020/internal/model"
+)
+
+const (
+	// rejectionSamplesCap is the maximum number of distinct offending values kept per rejection reason
+	rejectionSamplesCap = 3
+
+	// rejectionSampleLen is the number of characters long offending values (e.g. malformed lines) are truncated to
+	rejectionSampleLen = 120
+)
+
+// GetFileStats returns how many records every fed input contributed, sorted by input name. must be called after
+// Finish
+func (p *Processor) GetFileStats() []model.FileStats {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	fileStats := make([]model.FileStats, 0, len(p.fileStats))
+	for _, stats := range p.fileStats {
+		fileStats = append(fileStats, *stats)
+	}
+
+	sort.Slice(fileStats, func(i, j int) bool {
+		return fileStats[i].File < fileStats[j].File
+	})
+	return fileStats
+}
+
+// GetRejectedCount returns the number of records rejected across all the fed inputs
+func (p *Processor) GetRejectedCount() int {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	var rejected int
+	for _, stats := range p.fileStats {
+		rejected += stats.Rejected
+	}
+	return rejected
+}
+
+// GetDataQuality returns how many records were read and accepted across all the fed inputs and why the rest of them
+// were rejected. must be called after Finish
+func (p *Processor) GetDataQuality() model.DataQuality {
+	p.statsMu.Lock()
+	defer p.statsMu.Unlock()
+
+	var dataQuality model.DataQuality
+	for _, stats := range p.fileStats {
+		dataQuality.RecordsRead += stats.Records
+		dataQuality.RecordsAccepted += stats.Accepted
+	}
+
+	dataQuality.Rejections = make([]model.Rejection, 0, len(model.RejectionReasons))
+	for _, reason := range model.RejectionReasons {
+		dataQuality.Rejections = append(dataQuality.Rejections, *p.rejections[reason])
+	}
+	return dataQuality
+}
+
+// getFileStats returns the statistics of the named input, statsMu must be held by the caller
+func (p *Processor) getFileStats(name string) *model.FileStats {
+	stats, ok := p.fileStats[name]
+	if !ok {
+		stats = &model.FileStats{File: name}
+		p.fileStats[name] = stats
+	}
+	return stats
+}
+
+// recordRejection counts the rejected record under its reason and keeps its offending value as a sample,
+// statsMu must be held by the caller
+func (p *Processor) recordRejection(rejected *model.RejectedRecipe) {
+	rejection := p.rejections[rejected.Code]
+	rejection.Count++
+
+	if len(rejection.Samples) >= rejectionSamplesCap {
+		return
+	}
+
+	sample := offendingValue(rejected)
+	if runes := []rune(sample); len(runes) > rejectionSampleLen {
+		sample = string(runes[:rejectionSampleLen]) + "..."
+	}
+	for _, existing := range rejection.Samples {
+		if existing == sample {
+			return
+		}
+	}
+	rejection.Samples = append(rejection.Samples, sample)
+}
+
+// newRejections initializes the rejection counters of every reason, so that reasons nothing was rejected for are
+// reported as well
+func newRejections() map[model.RejectionReason]*model.Rejection {
+	rejections := make(map[model.RejectionReason]*model.Rejection, len(model.RejectionReasons))
+	for _, reason := range model.RejectionReasons {
+		rejections[reason] = &model.Rejection{Reason: reason, Samples: []string{}}
+	}
+	return rejections
+}
+
+// rejectionReason maps the error a recipe failed processing with to the reason it is reported under
+func rejectionReason(err error) model.RejectionReason {
+	switch {
+	case errors.Is(err, ErrMissingField):
+		return model.RejectionMissingField
+	case errors.Is(err, ErrInvalidDelivery):
+		return model.RejectionInvalidDelivery
+	case errors.Is(err, aggregate.ErrPostcodeTooLong):
+		return model.RejectionPostcodeTooLong
+	case errors.Is(err, aggregate.ErrRecipeNameTooLong):
+		return model.RejectionRecipeNameTooLong
+	default:
+		return model.RejectionMalformedRecord
+	}
+}
+
+// offendingValue returns the value that caused the record to be rejected
+func offendingValue(rejected *model.RejectedRecipe) string {
+	recipe := rejected.Recipe
+	if recipe == nil {
+		return rejected.Raw
+	}
+
+	switch rejected.Code {
+	case model.RejectionMissingField:
+		var missing []string
+		for field, value := range map[string]string{
+			"postcode": recipe.Postcode, "delivery": recipe.Delivery, "recipe": recipe.Recipe,
+		} {
+			if value == "" {
+				missing = append(missing, field)
+			}
+		}
+		sort.Strings(missing)
+		return strings.Join(missing, ",")
+	case model.RejectionInvalidDelivery:
+		return recipe.Delivery
+	case model.RejectionPostcodeTooLong:
+		return recipe.Postcode
+	case model.RejectionRecipeNameTooLong:
+		return recipe.Recipe
+	default:
+		return rejected.Raw
+	}
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"os"
+
 	"github.com/davido912-recipe-count-test-2020/cmd"
 )
 
 func main() {
-	cmd.Run()
+	if err := cmd.Run(); err != nil {
+		os.Exit(1)
+	}
 }